@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http_server/middleware/auth"
+	"url-shortener/internal/lib/apikey"
+)
+
+// runKeys выполняет подкоманду keys:
+//
+//	url_shortener keys create -user NAME [-role user|admin]
+//	url_shortener keys list
+//	url_shortener keys revoke -id N
+//
+// Выпущенный ключ печатается в stdout один раз: в хранилище остается только его хэш.
+func runKeys(log *slog.Logger, cfg *config.Config, args []string) error {
+	const op = "main.runKeys"
+
+	if len(args) == 0 {
+		return fmt.Errorf("%s: %w", op, errors.New("expected create, list or revoke"))
+	}
+
+	flags := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	user := flags.String("user", "", "owner of the new key")
+	role := flags.String("role", auth.RoleUser, "role of the new key: user or admin")
+	id := flags.Int64("id", 0, "id of the key to revoke")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	repo, err := setupStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		if *user == "" {
+			return fmt.Errorf("%s: %w", op, errors.New("-user is required"))
+		}
+		if *role != auth.RoleUser && *role != auth.RoleAdmin {
+			return fmt.Errorf("%s: unknown role %q", op, *role)
+		}
+
+		secret, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		key, err := repo.CreateAPIKey(ctx, *user, *role, prefix, hash)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("api key created",
+			slog.Int64("key_id", key.ID),
+			slog.String("user", key.User),
+			slog.String("role", key.Role),
+		)
+
+		fmt.Fprintln(os.Stdout, secret)
+	case "list":
+		keys, err := repo.ListAPIKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, key := range keys {
+			status := "active"
+			if !key.RevokedAt.IsZero() {
+				status = "revoked"
+			}
+
+			log.Info("api key",
+				slog.Int64("key_id", key.ID),
+				slog.String("user", key.User),
+				slog.String("role", key.Role),
+				slog.String("prefix", key.Prefix),
+				slog.Time("created_at", key.CreatedAt),
+				slog.String("status", status),
+			)
+		}
+	case "revoke":
+		if *id <= 0 {
+			return fmt.Errorf("%s: %w", op, errors.New("-id is required"))
+		}
+
+		if err = repo.RevokeAPIKey(ctx, *id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("api key revoked", slog.Int64("key_id", *id))
+	default:
+		return fmt.Errorf("%s: unknown keys command %q", op, args[0])
+	}
+
+	return nil
+}
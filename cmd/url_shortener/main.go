@@ -1,21 +1,36 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"url-shortener/internal/clicks"
 	"url-shortener/internal/config"
-	"url-shortener/internal/http_server/handlers/redirect"
-	"url-shortener/internal/http_server/handlers/url/delete"
+	"url-shortener/internal/http_server/handlers/health"
 	"url-shortener/internal/http_server/handlers/url/save"
-	"url-shortener/internal/http_server/middleware/logger"
+	"url-shortener/internal/http_server/middleware/auth"
+	"url-shortener/internal/http_server/router"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/jwtauth"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/tracing"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/reaper"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/cached"
+	"url-shortener/internal/storage/instrumented"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/postgres"
 	"url-shortener/internal/storage/sqlite"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
+	"url-shortener/internal/storage/traced"
 )
 
 const (
@@ -24,64 +39,334 @@ const (
 	envProd  = "prod"
 )
 
+const (
+	driverSQLite   = "sqlite"
+	driverPostgres = "postgres"
+	driverMemory   = "memory"
+)
+
 func main() {
+	// Хэш пароля нужен, чтобы заполнить конфиг, поэтому сам конфиг не читается
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		if err := runHashPassword(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "hash-password:", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	cfg := config.MustLoad()
 
 	log := setupLogger(cfg.Env)
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(log, cfg, os.Args[2:]); err != nil {
+				log.Error("migration failed", sl.Err(err))
+				os.Exit(1)
+			}
+
+			return
+		case "export":
+			if err := runExport(log, cfg, os.Args[2:]); err != nil {
+				log.Error("export failed", sl.Err(err))
+				os.Exit(1)
+			}
+
+			return
+		case "import":
+			if err := runImport(log, cfg, os.Args[2:]); err != nil {
+				log.Error("import failed", sl.Err(err))
+				os.Exit(1)
+			}
+
+			return
+		case "keys":
+			if err := runKeys(log, cfg, os.Args[2:]); err != nil {
+				log.Error("keys command failed", sl.Err(err))
+				os.Exit(1)
+			}
+
+			return
+		}
+	}
+
 	log.Info("starting url-shortener", slog.String("env", cfg.Env))
 	log.Debug("debug messages are enable")
+	log.Info("using storage", slog.String("driver", cfg.Storage.Driver))
 
-	storage, err := sqlite.New(cfg.StoragePath)
+	if err := auth.CheckUsers(router.Users(cfg.HTTPServer)); err != nil {
+		log.Error("invalid http_server users", sl.Err(err))
+		os.Exit(1)
+	}
+	if _, err := clientip.ParseTrusted(cfg.RateLimit.TrustedProxies); err != nil {
+		log.Error("invalid http_server.rate_limit.trusted_proxies", sl.Err(err))
+		os.Exit(1)
+	}
+	if cfg.Tracing.SampleRatio <= 0 || cfg.Tracing.SampleRatio > 1 {
+		log.Error("invalid tracing.sample_ratio, want a value in (0, 1]",
+			slog.Float64("sample_ratio", cfg.Tracing.SampleRatio))
+		os.Exit(1)
+	}
+	if cfg.Cache.Enabled && (cfg.Cache.Size <= 0 || cfg.Cache.TTL <= 0 || cfg.Cache.NegativeTTL <= 0) {
+		log.Error("invalid cache, size, ttl and negative_ttl must be positive")
+		os.Exit(1)
+	}
+	if cfg.HTTPServer.ExportTimeout <= 0 {
+		log.Error("invalid http_server.export_timeout, must be positive")
+		os.Exit(1)
+	}
+	aliases, err := newAliasGenerator(cfg.Aliases)
 	if err != nil {
-		log.Error("failed to init storage", sl.Err(err))
+		log.Error("invalid aliases", sl.Err(err))
 		os.Exit(1)
 	}
+	if cfg.HTTPServer.User != "" {
+		log.Warn("http_server.user with a plain text password is deprecated, use http_server.users")
+	}
+
+	// Первый SIGINT или SIGTERM запускает плавную остановку, второй завершает процесс сразу
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	router := chi.NewRouter()
+	var tokens auth.TokenVerifier
 
-	router.Use(middleware.RequestID)
-	//router.Use(middleware.Logger)
-	router.Use(logger.New(log))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
-	router.Use(middleware.AllowContentType("application/json"))
-	router.Use(render.SetContentType(render.ContentTypeJSON))
+	if cfg.JWT.JWKS != "" {
+		verifier, err := jwtauth.New(ctx, log, jwtauth.Options{
+			JWKS:            cfg.JWT.JWKS,
+			Issuer:          cfg.JWT.Issuer,
+			Audience:        cfg.JWT.Audience,
+			UserClaim:       cfg.JWT.UserClaim,
+			RolesClaim:      cfg.JWT.RolesClaim,
+			AdminRole:       cfg.JWT.AdminRole,
+			Leeway:          cfg.JWT.Leeway,
+			RefreshInterval: cfg.JWT.RefreshInterval,
+		})
+		if err != nil {
+			log.Error("failed to init jwt verifier", sl.Err(err))
+			os.Exit(1)
+		}
 
-	router.Route("/delete", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url_shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
+		log.Info("jwt authentication enabled", slog.String("jwks", cfg.JWT.JWKS))
 
-		r.Delete("/{alias:.+}", delete.Delete(log, storage))
+		tokens = verifier
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
 	})
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
+
+	log.Info("tracing configured", slog.String("exporter", cfg.Tracing.Exporter))
+
+	storage, err := setupStorage(cfg)
+	if err != nil {
+		log.Error("failed to init storage", sl.Err(err))
+		os.Exit(1)
+	}
 
-	router.Route("/", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url_shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
+	err = run(ctx, stop, log, cfg, storage, aliases, tokens)
 
-		r.Post("/save", save.New(log, storage))
+	if closeErr := storage.Close(); closeErr != nil {
+		log.Error("failed to close storage", sl.Err(closeErr))
+	} else {
+		log.Info("storage closed")
+	}
 
-		r.Get("/{alias}", redirect.Get(log, storage))
-	})
+	// После хранилища, чтобы выгрузить и spans последнего сброса переходов
+	tracingCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	if tracingErr := shutdownTracing(tracingCtx); tracingErr != nil {
+		log.Error("failed to flush traces", sl.Err(tracingErr))
+	}
+	cancel()
+
+	if err != nil {
+		log.Error("server failed", sl.Err(err))
+		os.Exit(1)
+	}
+
+	log.Info("server stopped")
+}
+
+// run запускает фоновые задачи и http-серверы и работает до отмены ctx или
+// ошибки сервера. Затем серверы перестают принимать соединения и в пределах
+// shutdown_timeout дожидаются начатых запросов, после чего останавливаются
+// фоновые задачи: они дописывают в хранилище накопленные переходы.
+// Хранилище закрывает вызывающий.
+func run(
+	ctx context.Context,
+	stop context.CancelFunc,
+	log *slog.Logger,
+	cfg *config.Config,
+	repo storage.Repository,
+	aliases save.AliasGenerator,
+	tokens auth.TokenVerifier,
+) error {
+	m := metrics.New()
+	repo = traced.New(instrumented.New(repo, m), cfg.Storage.Driver)
+
+	// Поверх метрик и трассировки, чтобы в них попадали только обращения к базе
+	if cfg.Cache.Enabled {
+		cache := cached.New(repo, cached.Options{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+		})
+		m.RegisterURLCache(cache.Stats)
 
-	log.Info("server started", slog.String("address", cfg.Address))
+		log.Info("url cache enabled", slog.Int("size", cfg.Cache.Size), slog.String("ttl", cfg.Cache.TTL.String()))
 
-	srv := &http.Server{
-		Addr:         cfg.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.HTTPServer.Timeout,
-		WriteTimeout: cfg.HTTPServer.Timeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		repo = cache
 	}
 
-	err = srv.ListenAndServe()
+	expiredReaper, err := reaper.New(log, repo, cfg.Reaper.Interval, cfg.Reaper.Mode)
 	if err != nil {
-		log.Error("failed to start server", sl.Err(err))
+		return fmt.Errorf("init reaper: %w", err)
+	}
+
+	expiredReaper.Start()
+	defer expiredReaper.Stop()
+
+	clickCounter := clicks.NewCounter(log, repo, cfg.Clicks.FlushInterval)
+	clickCounter.Start()
+	defer clickCounter.Stop()
+
+	var (
+		clickRecorder clicks.Recorder = clickCounter
+		checks        []health.Check
+	)
+
+	if cfg.Clicks.Events.Enabled {
+		// Список проверен при запуске
+		trusted, _ := clientip.ParseTrusted(cfg.RateLimit.TrustedProxies)
+
+		clickQueue, err := clicks.NewQueue(log, repo, clicks.QueueOptions{
+			Size:           cfg.Clicks.Events.QueueSize,
+			BatchSize:      cfg.Clicks.Events.BatchSize,
+			FlushInterval:  cfg.Clicks.Events.FlushInterval,
+			Overflow:       cfg.Clicks.Events.Overflow,
+			IPHashSalt:     cfg.Clicks.Events.IPHashSalt,
+			TrustedProxies: trusted,
+		})
+		if err != nil {
+			return fmt.Errorf("init click events queue: %w", err)
+		}
+
+		m.RegisterClickQueue(clickQueue.Stats)
+
+		clickQueue.Start()
+		defer clickQueue.Stop()
+
+		clickRecorder = clicks.Multi{clickCounter, clickQueue}
+		checks = append(checks, health.Check{Name: "click_events_queue", Check: clickQueue.Check})
+	}
+
+	servers := map[string]*http.Server{
+		"server": newServer(cfg.Address, cfg.HTTPServer, router.New(log, cfg.HTTPServer, repo, aliases, clickRecorder, tokens, m, checks...)),
+	}
+
+	if cfg.Redirect.Address != "" {
+		servers["redirect server"] = newServer(
+			cfg.Redirect.Address, cfg.HTTPServer,
+			router.NewRedirect(log, cfg.HTTPServer, repo, clickRecorder, tokens, m, checks...),
+		)
+	}
+
+	serveErr := make(chan error, len(servers))
+
+	for name, srv := range servers {
+		go func() {
+			log.Info(name+" started", slog.String("address", srv.Addr))
+
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("%s: %w", name, err)
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Info("shutdown signal received", slog.String("timeout", cfg.HTTPServer.ShutdownTimeout.String()))
+	case err = <-serveErr:
 	}
 
-	log.Error("server stopped")
+	// Повторный сигнал больше не перехватывается и завершает процесс
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for name, srv := range servers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Error("failed to drain "+name+", closing connections", sl.Err(err))
+				_ = srv.Close()
+
+				return
+			}
+
+			log.Info(name + " stopped")
+		}()
+	}
+
+	wg.Wait()
+
+	return err
+}
+
+func newServer(address string, cfg config.HTTPServer, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         address,
+		Handler:      handler,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+
+func setupStorage(cfg *config.Config) (storage.Repository, error) {
+	switch cfg.Storage.Driver {
+	case driverSQLite:
+		return sqlite.New(cfg.StoragePath, sqliteOptions(cfg.Storage))
+	case driverPostgres:
+		return postgres.New(cfg.Storage.DSN, postgres.Options{QueryTimeout: cfg.Storage.QueryTimeout})
+	case driverMemory:
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+func newAliasGenerator(cfg config.Aliases) (*random.AliasGenerator, error) {
+	return random.NewAliasGenerator(random.AliasOptions{
+		Alphabet: cfg.Alphabet,
+		Length:   cfg.Length,
+		Prefix:   cfg.Prefix,
+	})
+}
+
+func sqliteOptions(cfg config.Storage) sqlite.Options {
+	return sqlite.Options{
+		QueryTimeout: cfg.QueryTimeout,
+		JournalMode:  cfg.SQLite.JournalMode,
+		BusyTimeout:  cfg.SQLite.BusyTimeout,
+		Synchronous:  cfg.SQLite.Synchronous,
+		ReadConns:    cfg.SQLite.ReadConns,
+	}
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"url-shortener/internal/config"
+	"url-shortener/internal/storage/migrate"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/storage/sqlite"
+)
+
+// runMigrate выполняет подкоманду migrate:
+//
+//	url_shortener migrate [-down N] [-dry-run]
+//
+// Без флагов применяет все ожидающие миграции, с -down откатывает N последних.
+// С -dry-run только печатает шаги, которые были бы выполнены.
+func runMigrate(log *slog.Logger, cfg *config.Config, args []string) error {
+	const op = "main.runMigrate"
+
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	down := flags.Int("down", 0, "number of migrations to roll back")
+	dryRun := flags.Bool("dry-run", false, "print pending steps without applying them")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrator, closeDB, err := setupMigrator(cfg, *dryRun)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer closeDB()
+
+	version, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("op", op), slog.Int("current_version", version))
+
+	direction := "up"
+	var steps []migrate.Migration
+
+	switch {
+	case *down > 0 && *dryRun:
+		direction = "down"
+		steps, err = migrator.Planned(*down)
+	case *down > 0:
+		direction = "down"
+		steps, err = migrator.Down(*down)
+	case *dryRun:
+		steps, err = migrator.Pending()
+	default:
+		steps, err = migrator.Up()
+	}
+
+	for _, step := range steps {
+		log.Info("migration step",
+			slog.String("direction", direction),
+			slog.Int("version", step.Version),
+			slog.String("name", step.Name),
+			slog.Bool("dry_run", *dryRun),
+		)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(steps) == 0 {
+		log.Info("schema is up to date")
+	}
+
+	return nil
+}
+
+// migratable — хранилище, схемой которого управляют миграции.
+type migratable interface {
+	Migrator() (*migrate.Migrator, error)
+	Close() error
+}
+
+// setupMigrator открывает базу без применения миграций, с readOnly — не
+// создавая файл sqlite. Вызывающий закрывает ее функцией closeDB.
+func setupMigrator(cfg *config.Config, readOnly bool) (m *migrate.Migrator, closeDB func() error, err error) {
+	var s migratable
+
+	switch {
+	case cfg.Storage.Driver == driverSQLite && readOnly:
+		s, err = sqlite.OpenReadOnly(cfg.StoragePath, sqliteOptions(cfg.Storage))
+	case cfg.Storage.Driver == driverSQLite:
+		s, err = sqlite.Open(cfg.StoragePath, sqliteOptions(cfg.Storage))
+	case cfg.Storage.Driver == driverPostgres:
+		s, err = postgres.Open(cfg.Storage.DSN, postgres.Options{})
+	default:
+		return nil, nil, fmt.Errorf("storage driver %q does not support migrations", cfg.Storage.Driver)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err = s.Migrator()
+	if err != nil {
+		_ = s.Close()
+		return nil, nil, err
+	}
+
+	return m, s.Close, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"url-shortener/internal/lib/password"
+)
+
+// runHashPassword выполняет подкоманду hash-password:
+//
+//	url_shortener hash-password [-algo bcrypt|argon2id] < password.txt
+//
+// Пароль читается из первой строки stdin, чтобы не попасть в историю shell,
+// хэш печатается в stdout для поля http_server.users[].password_hash.
+func runHashPassword(args []string) error {
+	const op = "main.runHashPassword"
+
+	flags := flag.NewFlagSet("hash-password", flag.ContinueOnError)
+	algo := flags.String("algo", password.AlgoBcrypt, "hash algorithm: bcrypt or argon2id")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("%s: %w", op, errors.New("expected password on stdin"))
+	}
+
+	pass := strings.TrimRight(line, "\r\n")
+	if pass == "" {
+		return fmt.Errorf("%s: %w", op, errors.New("password is empty"))
+	}
+
+	hash, err := password.Hash(pass, *algo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	fmt.Fprintln(os.Stdout, hash)
+
+	return nil
+}
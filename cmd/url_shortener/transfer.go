@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"url-shortener/internal/config"
+	"url-shortener/internal/transfer"
+)
+
+// runExport выполняет подкоманду export:
+//
+//	url_shortener export [-format csv|jsonl] [-o file]
+//
+// Без -o пишет выгрузку в stdout.
+func runExport(log *slog.Logger, cfg *config.Config, args []string) (err error) {
+	const op = "main.runExport"
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", transfer.FormatCSV, "output format: csv or jsonl")
+	output := flags.String("o", "", "output file (stdout by default)")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	repo, err := setupStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer repo.Close()
+
+	var w io.Writer = os.Stdout
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		defer func() {
+			if closeErr := f.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("%s: %w", op, closeErr)
+			}
+		}()
+
+		w = f
+	}
+
+	count, err := transfer.Export(context.Background(), w, *format, "", repo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("urls exported", slog.String("format", *format), slog.Int("count", count))
+
+	return nil
+}
+
+// runImport выполняет подкоманду import:
+//
+//	url_shortener import [-format csv|jsonl] [-conflict skip|overwrite|rename] [-owner user] <file|->
+//
+// Вместо имени файла можно передать "-", тогда данные читаются из stdin.
+// Загрузка выполняется с правами администратора: владелец берется из колонки
+// owner, а -owner задает его строкам без нее; статистика ссылок восстанавливается.
+// Строки с ошибками пропускаются и попадают в лог.
+func runImport(log *slog.Logger, cfg *config.Config, args []string) error {
+	const op = "main.runImport"
+
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", transfer.FormatCSV, "input format: csv or jsonl")
+	conflict := flags.String("conflict", transfer.ConflictSkip, "existing alias policy: skip, overwrite or rename")
+	owner := flags.String("owner", "", "owner of imported urls without an owner column (admin-only by default)")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%s: %w", op, errors.New("expected exactly one input file"))
+	}
+
+	var r io.Reader = os.Stdin
+
+	if path := flags.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	aliases, err := newAliasGenerator(cfg.Aliases)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	repo, err := setupStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer repo.Close()
+
+	report, err := transfer.Import(context.Background(), r, transfer.ImportOptions{
+		Format:   *format,
+		Conflict: *conflict,
+		Owner:    *owner,
+		Admin:    true,
+		Aliases:  aliases,
+	}, repo)
+
+	for _, row := range report.Rows {
+		log.Warn("import row conflict",
+			slog.Int("line", row.Line),
+			slog.String("alias", row.Alias),
+			slog.String("action", row.Action),
+			slog.String("new_alias", row.NewAlias),
+			slog.String("error", row.Error),
+		)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("urls imported",
+		slog.Int("imported", report.Imported),
+		slog.Int("overwritten", report.Overwritten),
+		slog.Int("skipped", report.Skipped),
+		slog.Int("failed", report.Failed),
+	)
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package clicks
+
+import "net/http"
+
+type Recorder interface {
+	RecordClick(request *http.Request, alias string)
+}
+
+// Multi передает каждый переход всем recorders по очереди.
+type Multi []Recorder
+
+func (m Multi) RecordClick(request *http.Request, alias string) {
+	for _, recorder := range m {
+		recorder.RecordClick(request, alias)
+	}
+}
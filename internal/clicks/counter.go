@@ -0,0 +1,131 @@
+// Package clicks учитывает переходы по коротким ссылкам, не замедляя редирект:
+// переходы копятся в памяти и периодически пишутся в хранилище пачкой.
+package clicks
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type ClicksAdder interface {
+	AddClicks(ctx context.Context, clicks []storage.ClickCount) error
+}
+
+type Counter struct {
+	log      *slog.Logger
+	adder    ClicksAdder
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]storage.ClickCount
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewCounter(log *slog.Logger, adder ClicksAdder, interval time.Duration) *Counter {
+	return &Counter{
+		log:      log.With(slog.String("component", "clicks/counter")),
+		adder:    adder,
+		interval: interval,
+		pending:  make(map[string]storage.ClickCount),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// RecordClick учитывает переход по alias. Не обращается к хранилищу.
+func (c *Counter) RecordClick(_ *http.Request, alias string) {
+	now := time.Now()
+	key := strings.ToLower(alias)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	click := c.pending[key]
+	click.Alias = alias
+	click.Count++
+	click.LastAccessedAt = now
+	c.pending[key] = click
+}
+
+// Start запускает периодическую запись накопленных переходов.
+func (c *Counter) Start() {
+	c.log.Info("click counter started", slog.String("flush_interval", c.interval.String()))
+
+	go c.loop()
+}
+
+// Stop останавливает запись и сбрасывает в хранилище оставшиеся переходы.
+func (c *Counter) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	<-c.done
+}
+
+func (c *Counter) loop() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			_ = c.Flush(context.Background())
+			c.log.Info("click counter stopped")
+			return
+		case <-ticker.C:
+			_ = c.Flush(context.Background())
+		}
+	}
+}
+
+// Flush записывает накопленные переходы. При ошибке они возвращаются
+// в буфер и будут записаны при следующей попытке.
+func (c *Counter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+
+	batch := c.pending
+	c.pending = make(map[string]storage.ClickCount, len(batch))
+	c.mu.Unlock()
+
+	clicks := make([]storage.ClickCount, 0, len(batch))
+	for _, click := range batch {
+		clicks = append(clicks, click)
+	}
+
+	if err := c.adder.AddClicks(ctx, clicks); err != nil {
+		c.log.Error("failed to flush clicks", sl.Err(err), slog.Int("aliases", len(clicks)))
+		c.restore(batch)
+		return err
+	}
+
+	c.log.Debug("clicks flushed", slog.Int("aliases", len(clicks)))
+
+	return nil
+}
+
+func (c *Counter) restore(batch map[string]storage.ClickCount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, click := range batch {
+		if newer, ok := c.pending[key]; ok {
+			click.Count += newer.Count
+			click.LastAccessedAt = newer.LastAccessedAt
+		}
+
+		c.pending[key] = click
+	}
+}
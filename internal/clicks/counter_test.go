@@ -0,0 +1,95 @@
+package clicks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+type recordingAdder struct {
+	err     error
+	batches [][]storage.ClickCount
+}
+
+func (a *recordingAdder) AddClicks(_ context.Context, clicks []storage.ClickCount) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	a.batches = append(a.batches, clicks)
+
+	return nil
+}
+
+func TestCounter_Flush(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+
+	_, err := repo.SaveURL(ctx, "https://example.com", "Alias", time.Time{}, "")
+	require.NoError(t, err)
+
+	counter := clicks.NewCounter(slogdiscard.NewDiscardLogger(), repo, time.Hour)
+
+	counter.RecordClick(nil, "alias")
+	counter.RecordClick(nil, "ALIAS")
+	counter.RecordClick(nil, "Alias")
+
+	require.NoError(t, counter.Flush(ctx))
+
+	stats, err := repo.GetStats(ctx, "alias")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.Clicks)
+	require.WithinDuration(t, time.Now(), stats.LastAccessedAt, time.Second)
+
+	// Повторный сброс без новых переходов ничего не меняет
+	require.NoError(t, counter.Flush(ctx))
+
+	stats, err = repo.GetStats(ctx, "alias")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.Clicks)
+}
+
+func TestCounter_FlushErrorKeepsClicks(t *testing.T) {
+	ctx := context.Background()
+	adder := &recordingAdder{err: errors.New("storage is down")}
+
+	counter := clicks.NewCounter(slogdiscard.NewDiscardLogger(), adder, time.Hour)
+	counter.RecordClick(nil, "alias")
+
+	require.Error(t, counter.Flush(ctx))
+
+	counter.RecordClick(nil, "alias")
+
+	adder.err = nil
+	require.NoError(t, counter.Flush(ctx))
+
+	require.Len(t, adder.batches, 1)
+	require.Len(t, adder.batches[0], 1)
+	require.Equal(t, int64(2), adder.batches[0][0].Count)
+}
+
+func TestCounter_StopFlushesPending(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+
+	_, err := repo.SaveURL(ctx, "https://example.com", "alias", time.Time{}, "")
+	require.NoError(t, err)
+
+	counter := clicks.NewCounter(slogdiscard.NewDiscardLogger(), repo, time.Hour)
+	counter.Start()
+
+	counter.RecordClick(nil, "alias")
+	counter.Stop()
+
+	stats, err := repo.GetStats(ctx, "alias")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Clicks)
+}
@@ -0,0 +1,266 @@
+package clicks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Политика при переполнении очереди событий.
+const (
+	// OverflowDrop отбрасывает событие, редирект не ждет.
+	OverflowDrop = "drop"
+	// OverflowBlock ждет места в очереди, пока жив контекст запроса.
+	OverflowBlock = "block"
+)
+
+// ErrQueueSaturated — очередь событий почти заполнена: запись в хранилище
+// не успевает за переходами, и скоро события начнут теряться или задерживать редиректы.
+var ErrQueueSaturated = errors.New("click events queue is saturated")
+
+// ipHashSaltLength — длина случайной соли, если своя не задана.
+const ipHashSaltLength = 32
+
+// saturationThreshold — доля заполнения, с которой очередь считается перегруженной.
+const saturationThreshold = 0.9
+
+type ClickEventsSaver interface {
+	SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error
+}
+
+type QueueOptions struct {
+	Size          int
+	BatchSize     int
+	FlushInterval time.Duration
+	Overflow      string
+	// IPHashSalt — соль хеша адреса. Без соли SHA-256 от IPv4 обращается
+	// перебором всех адресов, поэтому пустая соль заменяется случайной
+	// на время жизни процесса.
+	IPHashSalt string
+	// TrustedProxies — прокси, которым можно верить в X-Forwarded-For и
+	// X-Real-IP, как в ограничении частоты. Без них за прокси у всех событий
+	// был бы один и тот же хеш адреса — адреса прокси.
+	TrustedProxies []netip.Prefix
+}
+
+// QueueStats — счетчики очереди с момента запуска.
+type QueueStats struct {
+	Enqueued int64
+	Dropped  int64
+	Written  int64
+	Failed   int64
+	Length   int
+	Capacity int
+}
+
+// Queue пишет подробные события переходов в хранилище через ограниченную очередь.
+type Queue struct {
+	log   *slog.Logger
+	saver ClickEventsSaver
+	opts  QueueOptions
+
+	events chan storage.ClickEvent
+
+	enqueued atomic.Int64
+	dropped  atomic.Int64
+	written  atomic.Int64
+	failed   atomic.Int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewQueue(log *slog.Logger, saver ClickEventsSaver, opts QueueOptions) (*Queue, error) {
+	const op = "clicks.NewQueue"
+
+	if opts.Overflow != OverflowDrop && opts.Overflow != OverflowBlock {
+		return nil, fmt.Errorf("%s: unknown overflow policy %q", op, opts.Overflow)
+	}
+
+	if opts.Size <= 0 || opts.BatchSize <= 0 || opts.FlushInterval <= 0 {
+		return nil, fmt.Errorf("%s: size, batch size and flush interval must be positive", op)
+	}
+
+	log = log.With(
+		slog.String("component", "clicks/queue"),
+		slog.String("overflow", opts.Overflow),
+	)
+
+	if opts.IPHashSalt == "" {
+		opts.IPHashSalt = random.NewRandomString(ipHashSaltLength)
+
+		log.Warn("ip_hash_salt is not set, using a random salt: ip hashes will not match across restarts")
+	}
+
+	return &Queue{
+		log:    log,
+		saver:  saver,
+		opts:   opts,
+		events: make(chan storage.ClickEvent, opts.Size),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// RecordClick ставит событие в очередь. При переполнении событие
+// отбрасывается или ожидает места в зависимости от политики.
+func (q *Queue) RecordClick(request *http.Request, alias string) {
+	event := storage.ClickEvent{
+		Alias:     alias,
+		Time:      time.Now(),
+		Referrer:  request.Referer(),
+		UserAgent: request.UserAgent(),
+		IPHash:    q.hashIP(clientip.FromRequest(request, q.opts.TrustedProxies)),
+		RequestID: middleware.GetReqID(request.Context()),
+	}
+
+	select {
+	case q.events <- event:
+		q.enqueued.Add(1)
+		return
+	default:
+	}
+
+	if q.opts.Overflow == OverflowBlock {
+		select {
+		case q.events <- event:
+			q.enqueued.Add(1)
+			return
+		case <-request.Context().Done():
+		case <-q.stop:
+		}
+	}
+
+	// Логируем первое отброшенное событие и далее каждое тысячное, чтобы не засорять лог
+	if dropped := q.dropped.Add(1); dropped%1000 == 1 {
+		q.log.Warn("click event dropped, queue is full",
+			slog.String("alias", alias),
+			slog.Int64("dropped_total", dropped),
+		)
+	}
+}
+
+func (q *Queue) Stats() QueueStats {
+	return QueueStats{
+		Enqueued: q.enqueued.Load(),
+		Dropped:  q.dropped.Load(),
+		Written:  q.written.Load(),
+		Failed:   q.failed.Load(),
+		Length:   len(q.events),
+		Capacity: cap(q.events),
+	}
+}
+
+// Check возвращает ErrQueueSaturated, если очередь заполнена на 90% и больше.
+func (q *Queue) Check(_ context.Context) error {
+	const op = "clicks.Queue.Check"
+
+	stats := q.Stats()
+	if float64(stats.Length) >= float64(stats.Capacity)*saturationThreshold {
+		return fmt.Errorf("%s: %d of %d: %w", op, stats.Length, stats.Capacity, ErrQueueSaturated)
+	}
+
+	return nil
+}
+
+func (q *Queue) Start() {
+	q.log.Info("click event queue started",
+		slog.Int("size", q.opts.Size),
+		slog.Int("batch_size", q.opts.BatchSize),
+	)
+
+	go q.loop()
+}
+
+// Stop прекращает прием событий и дописывает в хранилище то, что уже в очереди.
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() { close(q.stop) })
+	<-q.done
+}
+
+func (q *Queue) loop() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storage.ClickEvent, 0, q.opts.BatchSize)
+
+	for {
+		select {
+		case event := <-q.events:
+			batch = append(batch, event)
+			if len(batch) >= q.opts.BatchSize {
+				batch = q.write(batch)
+			}
+		case <-ticker.C:
+			batch = q.write(batch)
+		case <-q.stop:
+			q.drain(batch)
+
+			stats := q.Stats()
+			q.log.Info("click event queue stopped",
+				slog.Int64("written", stats.Written),
+				slog.Int64("dropped", stats.Dropped),
+				slog.Int64("failed", stats.Failed),
+			)
+			return
+		}
+	}
+}
+
+func (q *Queue) drain(batch []storage.ClickEvent) {
+	for {
+		select {
+		case event := <-q.events:
+			batch = append(batch, event)
+			if len(batch) >= q.opts.BatchSize {
+				batch = q.write(batch)
+			}
+		default:
+			q.write(batch)
+			return
+		}
+	}
+}
+
+// write сохраняет пачку событий и возвращает пустой буфер для следующей.
+func (q *Queue) write(batch []storage.ClickEvent) []storage.ClickEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := q.saver.SaveClickEvents(context.Background(), batch); err != nil {
+		q.failed.Add(int64(len(batch)))
+		q.log.Error("failed to write click events", sl.Err(err), slog.Int("count", len(batch)))
+	} else {
+		q.written.Add(int64(len(batch)))
+	}
+
+	return batch[:0]
+}
+
+func (q *Queue) hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(q.opts.IPHashSalt + ip))
+
+	return hex.EncodeToString(sum[:])
+}
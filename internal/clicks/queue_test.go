@@ -0,0 +1,224 @@
+package clicks_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+type eventsRecorder struct {
+	events []storage.ClickEvent
+}
+
+func (r *eventsRecorder) SaveClickEvents(_ context.Context, events []storage.ClickEvent) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func newQueue(t *testing.T, saver clicks.ClickEventsSaver, size int, overflow string) *clicks.Queue {
+	t.Helper()
+
+	q, err := clicks.NewQueue(slogdiscard.NewDiscardLogger(), saver, clicks.QueueOptions{
+		Size:          size,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Overflow:      overflow,
+		IPHashSalt:    "salt",
+	})
+	require.NoError(t, err)
+
+	return q
+}
+
+func newClickRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("Referer", "https://ref.example")
+	req.Header.Set("User-Agent", "test-agent")
+
+	return req
+}
+
+func TestQueue_WritesEventsOnStop(t *testing.T) {
+	saver := &eventsRecorder{}
+	q := newQueue(t, saver, 100, clicks.OverflowDrop)
+	q.Start()
+
+	for i := 0; i < 25; i++ {
+		q.RecordClick(newClickRequest(), "alias")
+	}
+
+	q.Stop()
+
+	require.Len(t, saver.events, 25)
+
+	event := saver.events[0]
+	assert.Equal(t, "alias", event.Alias)
+	assert.Equal(t, "https://ref.example", event.Referrer)
+	assert.Equal(t, "test-agent", event.UserAgent)
+	assert.NotEmpty(t, event.IPHash)
+	assert.NotContains(t, event.IPHash, "203.0.113.7")
+	assert.WithinDuration(t, time.Now(), event.Time, time.Second)
+
+	stats := q.Stats()
+	assert.Equal(t, int64(25), stats.Enqueued)
+	assert.Equal(t, int64(25), stats.Written)
+	assert.Zero(t, stats.Dropped)
+}
+
+// За доверенным прокси хешируется адрес клиента из X-Forwarded-For, а не
+// адрес прокси, иначе у всех событий был бы один хеш.
+func TestQueue_HashesClientIPBehindProxy(t *testing.T) {
+	saver := &eventsRecorder{}
+
+	q, err := clicks.NewQueue(slogdiscard.NewDiscardLogger(), saver, clicks.QueueOptions{
+		Size:           10,
+		BatchSize:      10,
+		FlushInterval:  time.Hour,
+		Overflow:       clicks.OverflowDrop,
+		IPHashSalt:     "salt",
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+	require.NoError(t, err)
+	q.Start()
+
+	for _, client := range []string{"203.0.113.7", "198.51.100.2"} {
+		req := newClickRequest()
+		req.RemoteAddr = "10.0.0.1:443"
+		req.Header.Set("X-Forwarded-For", client)
+
+		q.RecordClick(req, "alias")
+	}
+
+	// Тот же клиент напрямую, без прокси
+	q.RecordClick(newClickRequest(), "alias")
+
+	// Недоверенный источник не может подменить адрес заголовком
+	spoofed := newClickRequest()
+	spoofed.Header.Set("X-Forwarded-For", "198.51.100.2")
+	q.RecordClick(spoofed, "alias")
+
+	q.Stop()
+
+	require.Len(t, saver.events, 4)
+	assert.NotEqual(t, saver.events[0].IPHash, saver.events[1].IPHash)
+	assert.Equal(t, saver.events[0].IPHash, saver.events[2].IPHash)
+	assert.Equal(t, saver.events[0].IPHash, saver.events[3].IPHash)
+}
+
+// Без соли хеш IPv4 обращается перебором, поэтому пустая соль заменяется
+// случайной: хеш не совпадает с голым SHA-256 и различается между процессами.
+func TestQueue_EmptySaltIsReplaced(t *testing.T) {
+	hashes := make([]string, 0, 2)
+
+	for range 2 {
+		saver := &eventsRecorder{}
+
+		q, err := clicks.NewQueue(slogdiscard.NewDiscardLogger(), saver, clicks.QueueOptions{
+			Size:          10,
+			BatchSize:     10,
+			FlushInterval: time.Hour,
+			Overflow:      clicks.OverflowDrop,
+		})
+		require.NoError(t, err)
+		q.Start()
+
+		q.RecordClick(newClickRequest(), "alias")
+		q.Stop()
+
+		require.Len(t, saver.events, 1)
+		hashes = append(hashes, saver.events[0].IPHash)
+	}
+
+	unsalted := sha256.Sum256([]byte("203.0.113.7"))
+
+	assert.NotEqual(t, hex.EncodeToString(unsalted[:]), hashes[0])
+	assert.NotEqual(t, hashes[0], hashes[1])
+}
+
+func TestQueue_DropOnOverflow(t *testing.T) {
+	q := newQueue(t, &eventsRecorder{}, 2, clicks.OverflowDrop)
+
+	// Очередь не запущена, поэтому в нее помещаются только два события
+	for i := 0; i < 5; i++ {
+		q.RecordClick(newClickRequest(), "alias")
+	}
+
+	stats := q.Stats()
+	assert.Equal(t, int64(2), stats.Enqueued)
+	assert.Equal(t, int64(3), stats.Dropped)
+	assert.Equal(t, 2, stats.Length)
+	assert.Equal(t, 2, stats.Capacity)
+}
+
+func TestQueue_Check(t *testing.T) {
+	q := newQueue(t, &eventsRecorder{}, 10, clicks.OverflowDrop)
+
+	for i := 0; i < 8; i++ {
+		q.RecordClick(newClickRequest(), "alias")
+	}
+	require.NoError(t, q.Check(context.Background()))
+
+	q.RecordClick(newClickRequest(), "alias")
+	require.ErrorIs(t, q.Check(context.Background()), clicks.ErrQueueSaturated)
+}
+
+func TestQueue_BlockOnOverflowRespectsRequestContext(t *testing.T) {
+	q := newQueue(t, &eventsRecorder{}, 1, clicks.OverflowBlock)
+
+	q.RecordClick(newClickRequest(), "alias")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	q.RecordClick(newClickRequest().WithContext(ctx), "alias")
+
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Equal(t, int64(1), q.Stats().Dropped)
+}
+
+func TestQueue_BlockOnOverflowWaitsForSpace(t *testing.T) {
+	saver := &eventsRecorder{}
+	q := newQueue(t, saver, 1, clicks.OverflowBlock)
+
+	q.RecordClick(newClickRequest(), "alias")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.Start()
+	}()
+
+	q.RecordClick(newClickRequest(), "alias")
+	q.Stop()
+
+	assert.Len(t, saver.events, 2)
+	assert.Zero(t, q.Stats().Dropped)
+}
+
+func TestNewQueue_InvalidOptions(t *testing.T) {
+	_, err := clicks.NewQueue(slogdiscard.NewDiscardLogger(), &eventsRecorder{}, clicks.QueueOptions{
+		Size:          1,
+		BatchSize:     1,
+		FlushInterval: time.Second,
+		Overflow:      "ignore",
+	})
+	require.Error(t, err)
+
+	_, err = clicks.NewQueue(slogdiscard.NewDiscardLogger(), &eventsRecorder{}, clicks.QueueOptions{
+		Overflow: clicks.OverflowDrop,
+	})
+	require.Error(t, err)
+}
@@ -8,17 +8,168 @@ import (
 )
 
 type Config struct {
-	Env         string `yaml:"env" env:"ENV" env-default:"local" `
-	StoragePath string `yaml:"storage_path" env-default:"./storage/storage.db" env-required:"true"`
+	Env         string  `yaml:"env" env:"ENV" env-default:"local" `
+	StoragePath string  `yaml:"storage_path" env-default:"./storage/storage.db" env-required:"true"`
+	Storage     Storage `yaml:"storage"`
+	Cache       Cache   `yaml:"cache"`
+	Aliases     Aliases `yaml:"aliases"`
+	Reaper      Reaper  `yaml:"reaper"`
+	Clicks      Clicks  `yaml:"clicks"`
+	Tracing     Tracing `yaml:"tracing"`
 	HTTPServer  `yaml:"http_server"`
 }
 
+type Storage struct {
+	Driver       string        `yaml:"driver" env:"STORAGE_DRIVER" env-default:"sqlite"`
+	DSN          string        `yaml:"dsn" env:"STORAGE_DSN"`
+	QueryTimeout time.Duration `yaml:"query_timeout" env-default:"2s"`
+	SQLite       SQLite        `yaml:"sqlite"`
+}
+
+// SQLite — настройки соединений sqlite. Запись идет через одно соединение,
+// чтение — через отдельный пул из ReadConns соединений.
+type SQLite struct {
+	JournalMode string        `yaml:"journal_mode" env-default:"wal"`   // wal, delete, truncate, persist, memory, off
+	BusyTimeout time.Duration `yaml:"busy_timeout" env-default:"5s"`    // ожидание блокировки базы до SQLITE_BUSY
+	Synchronous string        `yaml:"synchronous" env-default:"normal"` // off, normal, full, extra
+	ReadConns   int           `yaml:"read_conns"`                       // 0 — по числу процессоров
+}
+
+// Cache — кэш переходов по коротким ссылкам в памяти процесса. Изменения
+// ссылок через этот процесс видны сразу, а сделанные другими инстансами —
+// не позже чем через TTL.
+type Cache struct {
+	Enabled     bool          `yaml:"enabled" env:"CACHE_ENABLED"`
+	Size        int           `yaml:"size" env-default:"10000"`       // алиасов, сверх — вытесняются давно не читанные
+	TTL         time.Duration `yaml:"ttl" env-default:"1m"`           // для найденных ссылок
+	NegativeTTL time.Duration `yaml:"negative_ttl" env-default:"10s"` // для ненайденных и истекших
+}
+
+// Aliases — вид алиасов, которые генерируются для ссылок без своего: Prefix и
+// Length случайных символов из Alphabet. Хранилища сравнивают алиасы без учета
+// регистра, так что буквы в разном регистре не добавляют вариантов.
+type Aliases struct {
+	Alphabet string `yaml:"alphabet"` // пусто — латинские буквы и цифры
+	Length   int    `yaml:"length" env-default:"6"`
+	Prefix   string `yaml:"prefix"`
+}
+
+// Reaper — фоновая очистка ссылок с истекшим сроком действия.
+type Reaper struct {
+	Interval time.Duration `yaml:"interval" env-default:"1m"`
+	Mode     string        `yaml:"mode" env-default:"delete"` // delete, archive
+}
+
+// Clicks — учет переходов: счетчики копятся в памяти и сбрасываются в хранилище раз в FlushInterval.
+type Clicks struct {
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"5s"`
+	Events        ClickEvents   `yaml:"events"`
+}
+
+// ClickEvents — подробные события переходов, которые пишутся в таблицу clicks через ограниченную очередь.
+type ClickEvents struct {
+	Enabled       bool          `yaml:"enabled" env-default:"true"`
+	QueueSize     int           `yaml:"queue_size" env-default:"10000"`
+	BatchSize     int           `yaml:"batch_size" env-default:"500"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+	Overflow      string        `yaml:"overflow" env-default:"drop"` // drop, block
+	// IPHashSalt — соль хеша IP-адреса. Если пуста, на каждый запуск берется
+	// случайная, и хеши одного клиента до и после перезапуска не совпадут.
+	IPHashSalt string `yaml:"ip_hash_salt" env:"CLICKS_IP_HASH_SALT"`
+}
+
+// Tracing — трассировка OpenTelemetry: span на каждый запрос и на каждый
+// вызов хранилища. Без экспорта trace_id все равно пишется в логи.
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"` // none, stdout, otlp
+	// Endpoint — адрес коллектора OTLP/HTTP, например http://localhost:4318.
+	// Если пуст, адрес берется из OTEL_EXPORTER_OTLP_ENDPOINT, а без нее
+	// exporter otlp не запустится.
+	Endpoint    string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string `yaml:"service_name" env-default:"url-shortener"`
+	// SampleRatio — доля трассируемых запросов, от 0 (не включая) до 1.
+	// Запросы с входящим traceparent следуют решению вызывающего.
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
-	User        string        `yaml:"user" env-required:"true"`
-	Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
+	// ShutdownTimeout — сколько ждать завершения начатых запросов после
+	// SIGINT или SIGTERM, прежде чем разорвать соединения.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+	// ExportTimeout ограничивает GET /export вместо Timeout: большая выгрузка
+	// идет дольше обычного запроса, но и она не должна длиться бесконечно.
+	ExportTimeout time.Duration `yaml:"export_timeout" env-default:"10m"`
+	// User и Password — устаревший единственный пользователь с паролем в
+	// открытом виде. Вместо них лучше задавать Users с хэшами паролей.
+	User      string    `yaml:"user"`
+	Password  string    `yaml:"password" env:"HTTP_SERVER_PASSWORD"`
+	Users     []User    `yaml:"users"`
+	JWT       JWT       `yaml:"jwt"`
+	Batch     Batch     `yaml:"batch"`
+	Redirect  Redirect  `yaml:"redirect"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+	Health    Health    `yaml:"health"`
+}
+
+type Health struct {
+	// Timeout ограничивает каждую проверку /readyz.
+	Timeout time.Duration `yaml:"timeout" env-default:"1s"`
+}
+
+// RateLimit — ограничение частоты запросов (token bucket) по пользователю,
+// а для анонимных запросов — по адресу клиента. Лимит без rps не действует.
+type RateLimit struct {
+	Save     Limit `yaml:"save"`     // POST /save, /save/batch, /import
+	Redirect Limit `yaml:"redirect"` // GET /{alias}
+	Delete   Limit `yaml:"delete"`   // DELETE /delete/{alias}
+	// TrustedProxies — адреса или подсети прокси, которым можно верить в
+	// X-Forwarded-For и X-Real-IP. Без них адрес клиента — адрес соединения.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type Limit struct {
+	RPS   float64 `yaml:"rps"`   // запросов в секунду в среднем
+	Burst int     `yaml:"burst"` // сколько запросов можно сделать подряд
+}
+
+// Redirect — переходы по коротким ссылкам (GET /{alias}). По умолчанию они
+// открыты без аутентификации и обслуживаются тем же сервером, что и API.
+// Если задан Address, переходы слушаются только на нем, а на основном адресе
+// остается API управления.
+type Redirect struct {
+	Address string `yaml:"address" env:"HTTP_SERVER_REDIRECT_ADDRESS"`
+	Auth    bool   `yaml:"auth"` // требовать аутентификацию, как для API
+}
+
+// User — пользователь Basic Auth. Хэш пароля (bcrypt или argon2id) выдает
+// команда url_shortener hash-password.
+type User struct {
+	Name         string `yaml:"name"`
+	PasswordHash string `yaml:"password_hash"`
+	Role         string `yaml:"role"` // user (по умолчанию), admin
+}
+
+// JWT — вход по токенам провайдера удостоверений (OIDC) в заголовке
+// "Authorization: Bearer" наравне с Basic Auth и ключами API. Пустой JWKS
+// отключает проверку токенов.
+type JWT struct {
+	JWKS            string        `yaml:"jwks" env:"HTTP_SERVER_JWT_JWKS"` // путь к файлу или URL
+	Issuer          string        `yaml:"issuer"`
+	Audience        string        `yaml:"audience"`
+	UserClaim       string        `yaml:"user_claim" env-default:"sub"`
+	RolesClaim      string        `yaml:"roles_claim" env-default:"roles"`
+	AdminRole       string        `yaml:"admin_role" env-default:"admin"`
+	Leeway          time.Duration `yaml:"leeway" env-default:"30s"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env-default:"1h"`
+}
+
+// Batch — пакетное сохранение ссылок через POST /save/batch.
+type Batch struct {
+	MaxSize int    `yaml:"max_size" env-default:"1000"`
+	Mode    string `yaml:"mode" env-default:"atomic"` // atomic, best_effort
 }
 
 func MustLoad() *Config {
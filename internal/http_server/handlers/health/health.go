@@ -0,0 +1,108 @@
+// Package health отдает состояние сервиса балансировщику и оркестратору:
+// /healthz — процесс жив, /readyz — сервис может обслуживать запросы.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Check — проверка одного компонента. Nil означает, что компонент исправен.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Live всегда отвечает 200: раз обработчик выполнился, процесс жив.
+// Зависимости не проверяются, чтобы недоступная база не приводила к
+// бесполезному перезапуску процесса.
+func Live() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		render.JSON(writer, request, resp.OK())
+	}
+}
+
+// Ready выполняет проверки параллельно, каждую не дольше timeout (0 — без
+// ограничения), и отвечает 503, если хотя бы одна не прошла. Подробности
+// ошибок пишутся только в лог: в ответе для каждого компонента есть лишь
+// статус и признак таймаута.
+func Ready(log *slog.Logger, timeout time.Duration, checks ...Check) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.health.Ready"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		components := make(map[string]Component, len(checks))
+
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+
+		for _, check := range checks {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				ctx, cancel := request.Context(), func() {}
+				if timeout > 0 {
+					ctx, cancel = context.WithTimeout(request.Context(), timeout)
+				}
+				defer cancel()
+
+				component := Component{Status: resp.StatusOk}
+
+				if err := check.Check(ctx); err != nil {
+					log.Warn("readiness check failed", slog.String("component", check.Name), sl.Err(err))
+
+					component = Component{Status: resp.StatusError, Error: "check failed"}
+					if ctx.Err() != nil {
+						component.Error = "timeout"
+					}
+				}
+
+				mu.Lock()
+				components[check.Name] = component
+				mu.Unlock()
+			}()
+		}
+
+		wg.Wait()
+
+		response := Response{Response: resp.OK(), Components: components}
+
+		for _, component := range components {
+			if component.Status != resp.StatusOk {
+				response.Response = resp.Error("not ready")
+				render.Status(request, http.StatusServiceUnavailable)
+
+				break
+			}
+		}
+
+		render.JSON(writer, request, response)
+	}
+}
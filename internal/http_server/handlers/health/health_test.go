@@ -0,0 +1,73 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/http_server/handlers/health"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLive(t *testing.T) {
+	rr := httptest.NewRecorder()
+	health.Live().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"OK"}`, rr.Body.String())
+}
+
+func TestReady(t *testing.T) {
+	ok := health.Check{Name: "storage", Check: func(context.Context) error { return nil }}
+	failed := health.Check{Name: "migrations", Check: func(context.Context) error {
+		return errors.New("schema has pending migrations")
+	}}
+	slow := health.Check{Name: "queue", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	cases := []struct {
+		name           string
+		checks         []health.Check
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Ready",
+			checks:         []health.Check{ok},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","components":{"storage":{"status":"OK"}}}`,
+		},
+		{
+			name:           "Failed check",
+			checks:         []health.Check{ok, failed},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"ERROR","error":"not ready","components":{` +
+				`"storage":{"status":"OK"},"migrations":{"status":"ERROR","error":"check failed"}}}`,
+		},
+		{
+			name:           "Timeout",
+			checks:         []health.Check{slow},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"ERROR","error":"not ready","components":{"queue":{"status":"ERROR","error":"timeout"}}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := health.Ready(slogdiscard.NewDiscardLogger(), 10*time.Millisecond, tc.checks...)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Package keys — административные обработчики ключей API: выпуск, список и отзыв.
+package keys
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// CreateRequest — параметры нового ключа. Без Role выпускается ключ с ролью user.
+type CreateRequest struct {
+	User string `json:"user" validate:"required"`
+	Role string `json:"role,omitempty" validate:"omitempty,oneof=user admin"`
+}
+
+// Key — ключ в ответах API. Сам ключ не хранится, поэтому его видно только
+// в ответе на выпуск.
+type Key struct {
+	ID        int64      `json:"id"`
+	User      string     `json:"user"`
+	Role      string     `json:"role"`
+	Prefix    string     `json:"prefix"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateResponse struct {
+	resp.Response
+	Key
+	APIKey string `json:"api_key"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Keys []Key `json:"keys"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=APIKeyCreator
+type APIKeyCreator interface {
+	CreateAPIKey(ctx context.Context, user, role, prefix, hash string) (storage.APIKey, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=APIKeyLister
+type APIKeyLister interface {
+	ListAPIKeys(ctx context.Context) ([]storage.APIKey, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=APIKeyRevoker
+type APIKeyRevoker interface {
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+// Create выпускает новый ключ для пользователя и роли из тела запроса.
+func Create(log *slog.Logger, keyCreator APIKeyCreator) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.keys.Create"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		var req CreateRequest
+
+		err := render.DecodeJSON(request.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("request body is empty"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err = validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+
+			if errors.As(err, &validateErr) {
+				log.Error("invalid request", sl.Err(err))
+
+				render.Status(request, http.StatusBadRequest)
+				render.JSON(writer, request, resp.ValidatorError(validateErr))
+			} else {
+				log.Error("unexpected error during validation", sl.Err(err))
+
+				render.Status(request, http.StatusInternalServerError)
+				render.JSON(writer, request, resp.Error("internal server error"))
+			}
+
+			return
+		}
+
+		if req.Role == "" {
+			req.Role = auth.RoleUser
+		}
+
+		secret, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", sl.Err(err))
+
+			render.Status(request, http.StatusInternalServerError)
+			render.JSON(writer, request, resp.Error("internal server error"))
+			return
+		}
+
+		key, err := keyCreator.CreateAPIKey(request.Context(), req.User, req.Role, prefix, hash)
+		if err != nil {
+			storageFailed(writer, request, log, err, "failed to create api key")
+			return
+		}
+
+		log.Info("api key created",
+			slog.Int64("key_id", key.ID),
+			slog.String("user", key.User),
+			slog.String("role", key.Role),
+		)
+
+		render.Status(request, http.StatusCreated)
+		render.JSON(writer, request, CreateResponse{
+			Response: resp.OK(),
+			Key:      toKey(key),
+			APIKey:   secret,
+		})
+	}
+}
+
+// List возвращает все ключи, включая отозванные.
+func List(log *slog.Logger, keyLister APIKeyLister) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.keys.List"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		keys, err := keyLister.ListAPIKeys(request.Context())
+		if err != nil {
+			storageFailed(writer, request, log, err, "failed to list api keys")
+			return
+		}
+
+		response := ListResponse{Response: resp.OK(), Keys: make([]Key, 0, len(keys))}
+		for _, key := range keys {
+			response.Keys = append(response.Keys, toKey(key))
+		}
+
+		render.JSON(writer, request, response)
+	}
+}
+
+// Revoke отзывает ключ по id из пути. Запросы с ним сразу перестают проходить.
+func Revoke(log *slog.Logger, keyRevoker APIKeyRevoker) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.keys.Revoke"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Error("invalid key id", slog.String("id", chi.URLParam(request, "id")))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("invalid key id"))
+			return
+		}
+
+		err = keyRevoker.RevokeAPIKey(request.Context(), id)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Info("api key not found", slog.Int64("key_id", id))
+
+			render.Status(request, http.StatusNotFound)
+			render.JSON(writer, request, resp.Error("api key not found"))
+			return
+		}
+		if err != nil {
+			storageFailed(writer, request, log, err, "failed to revoke api key")
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("key_id", id))
+
+		render.JSON(writer, request, resp.OK())
+	}
+}
+
+func toKey(key storage.APIKey) Key {
+	k := Key{
+		ID:        key.ID,
+		User:      key.User,
+		Role:      key.Role,
+		Prefix:    key.Prefix,
+		CreatedAt: key.CreatedAt.UTC(),
+	}
+
+	if !key.RevokedAt.IsZero() {
+		revokedAt := key.RevokedAt.UTC()
+		k.RevokedAt = &revokedAt
+	}
+
+	return k
+}
+
+// storageFailed отвечает на ошибку хранилища так же, как остальные обработчики.
+func storageFailed(writer http.ResponseWriter, request *http.Request, log *slog.Logger, err error, msg string) {
+	switch {
+	case errors.Is(err, storage.ErrQueryTimeout):
+		log.Error("storage timeout", sl.Err(err))
+
+		render.Status(request, http.StatusGatewayTimeout)
+		render.JSON(writer, request, resp.Error("storage timeout"))
+	case errors.Is(err, context.Canceled):
+		log.Warn("request canceled", sl.Err(err))
+
+		render.Status(request, http.StatusServiceUnavailable)
+		render.JSON(writer, request, resp.Error("request canceled"))
+	default:
+		log.Error(msg, sl.Err(err))
+
+		render.Status(request, http.StatusInternalServerError)
+		render.JSON(writer, request, resp.Error("internal server error"))
+	}
+}
@@ -0,0 +1,250 @@
+package keys_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/keys"
+	"url-shortener/internal/http_server/handlers/keys/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func makeErrorBody(msg string) string {
+	jsonBody, _ := json.Marshal(resp.Error(msg))
+	return string(jsonBody)
+}
+
+func TestCreateHandler(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name           string
+		body           string
+		callStorage    bool
+		role           string
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success",
+			body:           `{"user":"team-a"}`,
+			callStorage:    true,
+			role:           "user",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Admin role",
+			body:           `{"user":"ops","role":"admin"}`,
+			callStorage:    true,
+			role:           "admin",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Unknown role",
+			body:           `{"user":"team-a","role":"root"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field Role is invalid"),
+		},
+		{
+			name:           "Empty user",
+			body:           `{"user":""}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field User is a required field"),
+		},
+		{
+			name:           "Empty body",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("request body is empty"),
+		},
+		{
+			name:           "Storage timeout",
+			body:           `{"user":"team-a"}`,
+			callStorage:    true,
+			role:           "user",
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+		{
+			name:           "Internal error",
+			body:           `{"user":"team-a"}`,
+			callStorage:    true,
+			role:           "user",
+			mockError:      errors.New("internal storage error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyCreatorMock := mocks.NewAPIKeyCreator(t)
+
+			var prefix, hash string
+
+			if tc.callStorage {
+				keyCreatorMock.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("string"), tc.role, mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+					Run(func(args mock.Arguments) {
+						prefix, hash = args.String(3), args.String(4)
+					}).
+					Return(storage.APIKey{ID: 3, User: "team-a", Role: tc.role, Prefix: "usk_abcdefgh", CreatedAt: createdAt}, tc.mockError).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+
+			keys.Create(slogdiscard.NewDiscardLogger(), keyCreatorMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+				return
+			}
+
+			var body keys.CreateResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			assert.Equal(t, resp.StatusOk, body.Status)
+			assert.Equal(t, int64(3), body.ID)
+			assert.Equal(t, "team-a", body.User)
+			assert.Equal(t, tc.role, body.Role)
+			assert.True(t, strings.HasPrefix(body.APIKey, prefix), "prefix must be the start of the key")
+			assert.Equal(t, apikey.Hash(body.APIKey), hash, "only the hash of the key must be stored")
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name           string
+		mockKeys       []storage.APIKey
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Success",
+			mockKeys: []storage.APIKey{
+				{ID: 1, User: "team-a", Role: "user", Prefix: "usk_aaaaaaaa", CreatedAt: createdAt, RevokedAt: createdAt.Add(time.Hour)},
+				{ID: 2, User: "ops", Role: "admin", Prefix: "usk_bbbbbbbb", CreatedAt: createdAt},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","keys":[` +
+				`{"id":1,"user":"team-a","role":"user","prefix":"usk_aaaaaaaa","created_at":"2026-01-02T03:04:05Z","revoked_at":"2026-01-02T04:04:05Z"},` +
+				`{"id":2,"user":"ops","role":"admin","prefix":"usk_bbbbbbbb","created_at":"2026-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:           "No keys",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","keys":[]}`,
+		},
+		{
+			name:           "Request canceled",
+			mockError:      context.Canceled,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   makeErrorBody("request canceled"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyListerMock := mocks.NewAPIKeyLister(t)
+			keyListerMock.On("ListAPIKeys", mock.Anything).
+				Return(tc.mockKeys, tc.mockError).
+				Once()
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+			rr := httptest.NewRecorder()
+
+			keys.List(slogdiscard.NewDiscardLogger(), keyListerMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestRevokeHandler(t *testing.T) {
+	cases := []struct {
+		name           string
+		id             string
+		callStorage    bool
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Success",
+			id:             "5",
+			callStorage:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK"}`,
+		},
+		{
+			name:           "Not found",
+			id:             "5",
+			callStorage:    true,
+			mockError:      storage.ErrAPIKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   makeErrorBody("api key not found"),
+		},
+		{
+			name:           "Invalid id",
+			id:             "abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("invalid key id"),
+		},
+		{
+			name:           "Internal error",
+			id:             "5",
+			callStorage:    true,
+			mockError:      errors.New("internal storage error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyRevokerMock := mocks.NewAPIKeyRevoker(t)
+
+			if tc.callStorage {
+				keyRevokerMock.On("RevokeAPIKey", mock.Anything, int64(5)).
+					Return(tc.mockError).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/admin/keys/"+tc.id, nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			keys.Revoke(slogdiscard.NewDiscardLogger(), keyRevokerMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
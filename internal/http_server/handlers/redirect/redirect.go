@@ -1,6 +1,7 @@
 package redirect
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -16,16 +17,39 @@ import (
 
 //go:generate go run github.com/vektra/mockery/v2@v2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetURL(ctx context.Context, alias string) (string, error)
 }
 
-func Get(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// ClickRecorder учитывает успешные переходы. Реализация не должна блокировать редирект.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=ClickRecorder
+type ClickRecorder interface {
+	RecordClick(request *http.Request, alias string)
+}
+
+// Результаты поиска ссылки для ResultRecorder.
+const (
+	ResultHit     = "hit"
+	ResultMiss    = "miss"
+	ResultExpired = "expired"
+)
+
+// ResultRecorder учитывает, нашлась ли ссылка при переходе. Сбои хранилища
+// сюда не попадают: они видны в метриках хранилища.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=ResultRecorder
+type ResultRecorder interface {
+	RecordRedirect(result string)
+}
+
+func Get(log *slog.Logger, urlGetter URLGetter, clickRecorder ClickRecorder, results ResultRecorder) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		const op = "handlers.redirect.Get"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
 		)
 
 		alias := chi.URLParam(request, "alias")
@@ -38,15 +62,41 @@ func Get(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		retrievedURL, err := urlGetter.GetURL(alias)
+		retrievedURL, err := urlGetter.GetURL(request.Context(), alias)
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info("url not found", slog.String("alias", alias))
+			results.RecordRedirect(ResultMiss)
 
 			render.Status(request, http.StatusNotFound)
 			render.JSON(writer, request, resp.Error("url not found"))
 
 			return
 		}
+		if errors.Is(err, storage.ErrUrlExpired) {
+			log.Info("url expired", slog.String("alias", alias))
+			results.RecordRedirect(ResultExpired)
+
+			render.Status(request, http.StatusGone)
+			render.JSON(writer, request, resp.Error("url expired"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+
+			return
+		}
 		if err != nil {
 			log.Info("failed to get url", sl.Err(err))
 
@@ -57,6 +107,7 @@ func Get(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		}
 
 		log.Info("got url", slog.String("url", retrievedURL))
+		results.RecordRedirect(ResultHit)
 
 		parsedURL, parseErr := url.Parse(retrievedURL)
 		if parseErr != nil {
@@ -66,6 +117,8 @@ func Get(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
+		clickRecorder.RecordClick(request, alias)
+
 		http.Redirect(writer, request, parsedURL.String(), http.StatusFound)
 	}
 }
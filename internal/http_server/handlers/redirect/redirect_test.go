@@ -16,6 +16,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,6 +28,7 @@ type testCase struct {
 	expectedStatus   int
 	expectedLocation string
 	expectedBody     string
+	expectedResult   string
 }
 
 func TestRedirectHandler(t *testing.T) {
@@ -42,6 +44,7 @@ func TestRedirectHandler(t *testing.T) {
 			mockURL:          "https://google.com",
 			expectedStatus:   http.StatusFound,
 			expectedLocation: "https://google.com",
+			expectedResult:   redirect.ResultHit,
 		},
 		{
 			name:           "Empty alias",
@@ -53,8 +56,9 @@ func TestRedirectHandler(t *testing.T) {
 			name:           "Alias not found",
 			alias:          "non_existent_alias",
 			mockError:      storage.ErrUrlNotFound,
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusNotFound,
 			expectedBody:   makeErrorBody("url not found"),
+			expectedResult: redirect.ResultMiss,
 		},
 		{
 			name:           "Internal error from URLGetter",
@@ -63,12 +67,28 @@ func TestRedirectHandler(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   makeErrorBody("internal server error"),
 		},
+		{
+			name:           "Alias expired",
+			alias:          "expired_alias",
+			mockError:      storage.ErrUrlExpired,
+			expectedStatus: http.StatusGone,
+			expectedBody:   makeErrorBody("url expired"),
+			expectedResult: redirect.ResultExpired,
+		},
+		{
+			name:           "Storage timeout",
+			alias:          "slow_alias",
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
 		{
 			name:             "Success with alias needing URL encoding",
 			alias:            "alias with spaces",
 			mockURL:          "https://example.com/path_without_spaces",
 			expectedStatus:   http.StatusFound,
 			expectedLocation: "https://example.com/path_without_spaces",
+			expectedResult:   redirect.ResultHit,
 		},
 		{
 			name:             "Success with URL to redirect having spaces",
@@ -76,15 +96,26 @@ func TestRedirectHandler(t *testing.T) {
 			mockURL:          "https://example.com/target path with spaces",
 			expectedStatus:   http.StatusFound,
 			expectedLocation: "https://example.com/target%20path%20with%20spaces",
+			expectedResult:   redirect.ResultHit,
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			resultRecorderMock := mocks.NewResultRecorder(t)
+
+			if tc.expectedResult != "" {
+				resultRecorderMock.On("RecordRedirect", tc.expectedResult).Once()
+			}
+
+			if tc.expectedStatus == http.StatusFound {
+				clickRecorderMock.On("RecordClick", mock.Anything, tc.alias).Once()
+			}
 
 			if tc.mockURL != "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
+				urlGetterMock.On("GetURL", mock.Anything, tc.alias).
 					Return(tc.mockURL, tc.mockError).
 					Maybe()
 			}
@@ -103,7 +134,7 @@ func TestRedirectHandler(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			router := chi.NewRouter()
-			handler := redirect.Get(slogdiscard.NewDiscardLogger(), urlGetterMock)
+			handler := redirect.Get(slogdiscard.NewDiscardLogger(), urlGetterMock, clickRecorderMock, resultRecorderMock)
 			router.Get("/{alias}", handler)
 			router.Get("/", handler)
 			router.ServeHTTP(rr, req)
@@ -120,13 +151,13 @@ func TestRedirectHandler(t *testing.T) {
 
 			if tc.mockURL != "" || tc.mockError != nil {
 				if tc.alias != "" && tc.name != "Empty alias" {
-					urlGetterMock.AssertCalled(t, "GetURL", tc.alias)
+					urlGetterMock.AssertCalled(t, "GetURL", mock.Anything, tc.alias)
 				}
 			} else if tc.alias != "" && tc.name != "Empty alias" {
 			}
 
 			if tc.name == "Empty alias" {
-				urlGetterMock.AssertNotCalled(t, "GetURL", tc.alias)
+				urlGetterMock.AssertNotCalled(t, "GetURL", mock.Anything, tc.alias)
 			}
 		})
 	}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Response — статистика по ссылке. Счетчик переходов обновляется
+// асинхронно и может отставать на интервал сброса clicks.flush_interval.
+type Response struct {
+	resp.Response
+	Alias          string     `json:"alias"`
+	URL            string     `json:"url"`
+	Clicks         int64      `json:"clicks"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=StatsGetter
+type StatsGetter interface {
+	GetStats(ctx context.Context, alias string) (storage.URLStats, error)
+}
+
+func Get(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.stats.Get"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		alias := chi.URLParam(request, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("alias is empty"))
+			return
+		}
+
+		stats, ok := ownStats(log, writer, request, statsGetter, alias)
+		if !ok {
+			return
+		}
+
+		log.Info("got stats", slog.String("alias", stats.Alias), slog.Int64("clicks", stats.Clicks))
+
+		render.JSON(writer, request, Response{
+			Response:       resp.OK(),
+			Alias:          stats.Alias,
+			URL:            stats.URL,
+			Clicks:         stats.Clicks,
+			CreatedAt:      timePtr(stats.CreatedAt),
+			LastAccessedAt: timePtr(stats.LastAccessedAt),
+			ExpiresAt:      timePtr(stats.ExpiresAt),
+		})
+	}
+}
+
+// ownStats читает статистику alias и проверяет, что ссылка принадлежит
+// вызывающему: чужие ссылки, их адреса и переходы видит только администратор.
+// При отказе или ошибке сам отвечает клиенту и возвращает false.
+func ownStats(
+	log *slog.Logger,
+	writer http.ResponseWriter,
+	request *http.Request,
+	statsGetter StatsGetter,
+	alias string,
+) (storage.URLStats, bool) {
+	identity, ok := auth.FromContext(request.Context())
+	if !ok {
+		log.Error("request is not authenticated")
+
+		render.Status(request, http.StatusForbidden)
+		render.JSON(writer, request, resp.Error("access denied"))
+		return storage.URLStats{}, false
+	}
+
+	stats, err := statsGetter.GetStats(request.Context(), alias)
+	if errors.Is(err, storage.ErrUrlNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+
+		render.Status(request, http.StatusNotFound)
+		render.JSON(writer, request, resp.Error("url not found"))
+		return storage.URLStats{}, false
+	}
+	if errors.Is(err, storage.ErrQueryTimeout) {
+		log.Error("storage timeout", sl.Err(err))
+
+		render.Status(request, http.StatusGatewayTimeout)
+		render.JSON(writer, request, resp.Error("storage timeout"))
+		return storage.URLStats{}, false
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Warn("request canceled", sl.Err(err))
+
+		render.Status(request, http.StatusServiceUnavailable)
+		render.JSON(writer, request, resp.Error("request canceled"))
+		return storage.URLStats{}, false
+	}
+	if err != nil {
+		log.Error("failed to get stats", sl.Err(err))
+
+		render.Status(request, http.StatusInternalServerError)
+		render.JSON(writer, request, resp.Error("internal server error"))
+		return storage.URLStats{}, false
+	}
+
+	if !identity.IsAdmin() && stats.Owner != identity.Owner() {
+		log.Warn("alias belongs to another user", slog.String("alias", alias), slog.String("user", identity.User))
+
+		render.Status(request, http.StatusForbidden)
+		render.JSON(writer, request, resp.Error("access denied"))
+		return storage.URLStats{}, false
+	}
+
+	return stats, true
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,156 @@
+package stats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/stats"
+	"url-shortener/internal/http_server/handlers/stats/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestStatsHandler(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	accessedAt := createdAt.Add(time.Hour)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name           string
+		alias          string
+		identity       *auth.Identity
+		mockStats      storage.URLStats
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			mockStats: storage.URLStats{
+				Alias:          "test_alias",
+				URL:            "https://google.com",
+				Clicks:         42,
+				CreatedAt:      createdAt,
+				LastAccessedAt: accessedAt,
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"test_alias","url":"https://google.com","clicks":42,` +
+				`"created_at":"2026-01-02T03:04:05Z","last_accessed_at":"2026-01-02T04:04:05Z"}`,
+		},
+		{
+			name:  "Never accessed",
+			alias: "fresh",
+			mockStats: storage.URLStats{
+				Alias:     "fresh",
+				URL:       "https://google.com",
+				CreatedAt: createdAt,
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"fresh","url":"https://google.com","clicks":0,"created_at":"2026-01-02T03:04:05Z"}`,
+		},
+		{
+			name:     "Own alias",
+			alias:    "mine",
+			identity: &auth.Identity{User: "team-a", Role: auth.RoleUser},
+			mockStats: storage.URLStats{
+				Alias:     "mine",
+				URL:       "https://google.com",
+				Owner:     "team-a",
+				CreatedAt: createdAt,
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"mine","url":"https://google.com","clicks":0,"created_at":"2026-01-02T03:04:05Z"}`,
+		},
+		{
+			name:     "Another user's alias",
+			alias:    "theirs",
+			identity: &auth.Identity{User: "team-a", Role: auth.RoleUser},
+			mockStats: storage.URLStats{
+				Alias:  "theirs",
+				URL:    "https://google.com",
+				Owner:  "team-b",
+				Clicks: 42,
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   makeErrorBody("access denied"),
+		},
+		{
+			name:           "Alias not found",
+			alias:          "missing",
+			mockError:      storage.ErrUrlNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   makeErrorBody("url not found"),
+		},
+		{
+			name:           "Storage timeout",
+			alias:          "slow",
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+		{
+			name:           "Internal error",
+			alias:          "broken",
+			mockError:      errors.New("internal storage error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			statsGetterMock := mocks.NewStatsGetter(t)
+			statsGetterMock.On("GetStats", mock.Anything, tc.alias).
+				Return(tc.mockStats, tc.mockError).
+				Once()
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/"+tc.alias, nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", tc.alias)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			identity := auth.Identity{User: "admin", Role: auth.RoleAdmin}
+			if tc.identity != nil {
+				identity = *tc.identity
+			}
+			req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+
+			rr := httptest.NewRecorder()
+			stats.Get(slogdiscard.NewDiscardLogger(), statsGetterMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestStatsHandler_NotAuthenticated(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/stats/test_alias", nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("alias", "test_alias")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	stats.Get(slogdiscard.NewDiscardLogger(), mocks.NewStatsGetter(t)).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,198 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// MaxBuckets ограничивает размер ответа: например, 41 день с шагом в час.
+const MaxBuckets = 1000
+
+var intervals = map[string]time.Duration{
+	IntervalHour: time.Hour,
+	IntervalDay:  24 * time.Hour,
+}
+
+// defaultRanges — диапазон по умолчанию, если from не задан.
+var defaultRanges = map[string]time.Duration{
+	IntervalHour: 24 * time.Hour,
+	IntervalDay:  30 * 24 * time.Hour,
+}
+
+type Bucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+type TimeSeriesResponse struct {
+	resp.Response
+	Alias    string    `json:"alias,omitempty"`
+	Interval string    `json:"interval,omitempty"`
+	From     time.Time `json:"from,omitempty"`
+	To       time.Time `json:"to,omitempty"`
+	Buckets  []Bucket  `json:"buckets,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=ClickSeriesGetter
+type ClickSeriesGetter interface {
+	StatsGetter
+	ClickTimeSeries(ctx context.Context, alias string, from, to time.Time, bucket time.Duration) ([]storage.ClickBucket, error)
+}
+
+// TimeSeries возвращает число переходов по alias с разбивкой по часам или дням.
+// Параметры запроса: interval=hour|day, from и to в формате RFC 3339.
+// Интервалы без переходов возвращаются с нулевым значением. Как и Get,
+// отдает ряд только по своим ссылкам, если вызывающий не администратор.
+func TimeSeries(log *slog.Logger, seriesGetter ClickSeriesGetter) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.stats.TimeSeries"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		alias := chi.URLParam(request, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("alias is empty"))
+			return
+		}
+
+		query := request.URL.Query()
+
+		interval := query.Get("interval")
+		if interval == "" {
+			interval = IntervalHour
+		}
+
+		bucket, ok := intervals[interval]
+		if !ok {
+			log.Info("invalid interval", slog.String("interval", interval))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("field interval must be one of: hour, day"))
+			return
+		}
+
+		to := time.Now().UTC()
+		if raw := query.Get("to"); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				log.Info("invalid to", sl.Err(err))
+
+				render.Status(request, http.StatusBadRequest)
+				render.JSON(writer, request, resp.Error("field to must be a RFC 3339 time"))
+				return
+			}
+
+			to = parsed.UTC()
+		}
+
+		from := to.Add(-defaultRanges[interval])
+		if raw := query.Get("from"); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				log.Info("invalid from", sl.Err(err))
+
+				render.Status(request, http.StatusBadRequest)
+				render.JSON(writer, request, resp.Error("field from must be a RFC 3339 time"))
+				return
+			}
+
+			from = parsed.UTC()
+		}
+
+		from = alignToBucket(from, bucket)
+
+		if !from.Before(to) {
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("field from must be before to"))
+			return
+		}
+
+		if to.Sub(from)/bucket >= MaxBuckets {
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("time range is too large"))
+			return
+		}
+
+		if _, ok := ownStats(log, writer, request, seriesGetter, alias); !ok {
+			return
+		}
+
+		buckets, err := seriesGetter.ClickTimeSeries(request.Context(), alias, from, to, bucket)
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get click time series", sl.Err(err))
+
+			render.Status(request, http.StatusInternalServerError)
+			render.JSON(writer, request, resp.Error("internal server error"))
+			return
+		}
+
+		log.Info("got click time series", slog.String("alias", alias), slog.Int("buckets", len(buckets)))
+
+		render.JSON(writer, request, TimeSeriesResponse{
+			Response: resp.OK(),
+			Alias:    alias,
+			Interval: interval,
+			From:     from,
+			To:       to,
+			Buckets:  fillBuckets(buckets, from, to, bucket),
+		})
+	}
+}
+
+// alignToBucket выравнивает t по началу интервала от начала эпохи, как это делает хранилище.
+func alignToBucket(t time.Time, bucket time.Duration) time.Time {
+	size := int64(bucket / time.Second)
+
+	return time.Unix(t.Unix()/size*size, 0).UTC()
+}
+
+// fillBuckets дополняет непустые интервалы из хранилища нулевыми до полного ряда [from, to).
+func fillBuckets(buckets []storage.ClickBucket, from, to time.Time, bucket time.Duration) []Bucket {
+	counts := make(map[int64]int64, len(buckets))
+	for _, b := range buckets {
+		counts[b.Start.Unix()] = b.Clicks
+	}
+
+	series := make([]Bucket, 0, to.Sub(from)/bucket+1)
+	for start := from; start.Before(to); start = start.Add(bucket) {
+		series = append(series, Bucket{Start: start, Clicks: counts[start.Unix()]})
+	}
+
+	return series
+}
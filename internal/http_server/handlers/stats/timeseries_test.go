@@ -0,0 +1,170 @@
+package stats_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/stats"
+	"url-shortener/internal/http_server/handlers/stats/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestTimeSeriesHandler(t *testing.T) {
+	from := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name           string
+		query          string
+		owner          string
+		statsError     error
+		callsStats     bool
+		callsStorage   bool
+		mockBuckets    []storage.ClickBucket
+		mockError      error
+		expectedBucket time.Duration
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:         "Hourly with gaps",
+			query:        "?interval=hour&from=2026-01-02T00:30:00Z&to=2026-01-02T03:00:00Z",
+			owner:        "team-a",
+			callsStats:   true,
+			callsStorage: true,
+			mockBuckets: []storage.ClickBucket{
+				{Start: from, Clicks: 2},
+				{Start: from.Add(2 * time.Hour), Clicks: 5},
+			},
+			expectedBucket: time.Hour,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"test_alias","interval":"hour",` +
+				`"from":"2026-01-02T00:00:00Z","to":"2026-01-02T03:00:00Z","buckets":[` +
+				`{"start":"2026-01-02T00:00:00Z","clicks":2},` +
+				`{"start":"2026-01-02T01:00:00Z","clicks":0},` +
+				`{"start":"2026-01-02T02:00:00Z","clicks":5}]}`,
+		},
+		{
+			name:           "Daily",
+			query:          "?interval=day&from=2026-01-02T00:00:00Z&to=2026-01-04T00:00:00Z",
+			owner:          "team-a",
+			callsStats:     true,
+			callsStorage:   true,
+			mockBuckets:    []storage.ClickBucket{{Start: from.Add(24 * time.Hour), Clicks: 7}},
+			expectedBucket: 24 * time.Hour,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"test_alias","interval":"day",` +
+				`"from":"2026-01-02T00:00:00Z","to":"2026-01-04T00:00:00Z","buckets":[` +
+				`{"start":"2026-01-02T00:00:00Z","clicks":0},` +
+				`{"start":"2026-01-03T00:00:00Z","clicks":7}]}`,
+		},
+		{
+			name:           "Another user's alias",
+			query:          "?from=2026-01-02T00:00:00Z&to=2026-01-02T01:00:00Z",
+			owner:          "team-b",
+			callsStats:     true,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   makeErrorBody("access denied"),
+		},
+		{
+			name:           "Alias not found",
+			query:          "?from=2026-01-02T00:00:00Z&to=2026-01-02T01:00:00Z",
+			statsError:     storage.ErrUrlNotFound,
+			callsStats:     true,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   makeErrorBody("url not found"),
+		},
+		{
+			name:           "Invalid interval",
+			query:          "?interval=week",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field interval must be one of: hour, day"),
+		},
+		{
+			name:           "Invalid from",
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field from must be a RFC 3339 time"),
+		},
+		{
+			name:           "From after to",
+			query:          "?from=2026-01-03T00:00:00Z&to=2026-01-02T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field from must be before to"),
+		},
+		{
+			name:           "Range too large",
+			query:          "?interval=hour&from=2025-01-01T00:00:00Z&to=2026-01-01T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("time range is too large"),
+		},
+		{
+			name:           "Storage timeout",
+			query:          "?from=2026-01-02T00:00:00Z&to=2026-01-02T01:00:00Z",
+			owner:          "team-a",
+			callsStats:     true,
+			callsStorage:   true,
+			mockError:      storage.ErrQueryTimeout,
+			expectedBucket: time.Hour,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+		{
+			name:           "Internal error",
+			query:          "?from=2026-01-02T00:00:00Z&to=2026-01-02T01:00:00Z",
+			owner:          "team-a",
+			callsStats:     true,
+			callsStorage:   true,
+			mockError:      errors.New("internal storage error"),
+			expectedBucket: time.Hour,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			seriesGetterMock := mocks.NewClickSeriesGetter(t)
+			if tc.callsStats {
+				seriesGetterMock.On("GetStats", mock.Anything, "test_alias").
+					Return(storage.URLStats{Alias: "test_alias", Owner: tc.owner}, tc.statsError).
+					Once()
+			}
+			if tc.callsStorage {
+				seriesGetterMock.On("ClickTimeSeries", mock.Anything, "test_alias", mock.Anything, mock.Anything, tc.expectedBucket).
+					Return(tc.mockBuckets, tc.mockError).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/test_alias/clicks"+tc.query, nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", "test_alias")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "team-a", Role: auth.RoleUser}))
+
+			rr := httptest.NewRecorder()
+			stats.TimeSeries(slogdiscard.NewDiscardLogger(), seriesGetterMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
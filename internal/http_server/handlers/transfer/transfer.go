@@ -0,0 +1,235 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+	"url-shortener/internal/http_server/handlers/url/save"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+	linktransfer "url-shortener/internal/transfer"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ImportResponse — отчет о загрузке: счетчики и строки с конфликтами или ошибками.
+type ImportResponse struct {
+	resp.Response
+	linktransfer.Report
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=URLExporter
+type URLExporter interface {
+	ForEachURL(ctx context.Context, owner string, fn func(storage.URLStats) error) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=URLImporter
+type URLImporter interface {
+	SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error)
+	UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error
+	RestoreStats(ctx context.Context, stats storage.URLStats) error
+}
+
+func accessDenied(log *slog.Logger, writer http.ResponseWriter, request *http.Request) {
+	log.Warn("request without identity")
+
+	render.Status(request, http.StatusForbidden)
+	render.JSON(writer, request, resp.Error("access denied"))
+}
+
+// Export отдает ссылки в формате format=csv|jsonl (по умолчанию csv):
+// администратору — все, остальным — только свои.
+// Строки пишутся в ответ по мере чтения из хранилища, но не дольше timeout.
+func Export(log *slog.Logger, urlExporter URLExporter, timeout time.Duration) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.transfer.Export"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			accessDenied(log, writer, request)
+			return
+		}
+
+		format := request.URL.Query().Get("format")
+		if format == "" {
+			format = linktransfer.FormatCSV
+		}
+
+		contentType := linktransfer.ContentType(format)
+		if contentType == "" {
+			log.Info("invalid format", slog.String("format", format))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("field format must be one of: csv, jsonl"))
+			return
+		}
+
+		// Выгрузка большой таблицы может идти дольше http_server.timeout, но
+		// медленный клиент не должен держать соединение и обход базы вечно
+		ctx, cancel := context.WithTimeout(request.Context(), timeout)
+		defer cancel()
+
+		if err := http.NewResponseController(writer).SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			log.Warn("failed to extend write deadline", sl.Err(err))
+		}
+
+		out := &trackingWriter{w: writer}
+
+		writer.Header().Set("Content-Type", contentType)
+		writer.Header().Set("Content-Disposition", `attachment; filename="urls.`+format+`"`)
+
+		count, err := linktransfer.Export(ctx, out, format, identity.Owner(), urlExporter)
+		if err != nil && !out.written {
+			// Ответ еще не начат, поэтому ошибку можно отдать обычным JSON
+			writer.Header().Del("Content-Disposition")
+			exportFailed(log, writer, request, err)
+			return
+		}
+		if err != nil {
+			log.Error("export interrupted", slog.Int("exported", count), sl.Err(err))
+			return
+		}
+
+		log.Info("urls exported", slog.Int("count", count), slog.String("format", format))
+	}
+}
+
+func exportFailed(log *slog.Logger, writer http.ResponseWriter, request *http.Request, err error) {
+	switch {
+	case errors.Is(err, storage.ErrQueryTimeout):
+		log.Error("storage timeout", sl.Err(err))
+
+		render.Status(request, http.StatusGatewayTimeout)
+		render.JSON(writer, request, resp.Error("storage timeout"))
+	case errors.Is(err, context.Canceled):
+		log.Warn("request canceled", sl.Err(err))
+
+		render.Status(request, http.StatusServiceUnavailable)
+		render.JSON(writer, request, resp.Error("request canceled"))
+	default:
+		log.Error("failed to export urls", sl.Err(err))
+
+		render.Status(request, http.StatusInternalServerError)
+		render.JSON(writer, request, resp.Error("internal server error"))
+	}
+}
+
+// Import загружает ссылки из тела запроса. Формат берется из параметра
+// format=csv|jsonl или из Content-Type, политика конфликтов алиасов — из
+// conflict=skip|overwrite|rename (по умолчанию skip). Новые ссылки
+// записываются на вызывающего; перезаписать чужую может только администратор.
+// aliases выдает алиасы строкам без алиаса и переименованным ссылкам.
+func Import(log *slog.Logger, urlImporter URLImporter, aliases save.AliasGenerator) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.transfer.Import"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			accessDenied(log, writer, request)
+			return
+		}
+
+		query := request.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = formatFromContentType(request.Header.Get("Content-Type"))
+		}
+
+		conflict := query.Get("conflict")
+		if conflict == "" {
+			conflict = linktransfer.ConflictSkip
+		}
+
+		report, err := linktransfer.Import(request.Context(), request.Body, linktransfer.ImportOptions{
+			Format:   format,
+			Conflict: conflict,
+			Owner:    identity.User,
+			Admin:    identity.IsAdmin(),
+			Aliases:  aliases,
+		}, urlImporter)
+
+		var inputErr *linktransfer.InputError
+		if errors.As(err, &inputErr) {
+			log.Info("invalid import input", sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error(inputErr.Msg))
+			return
+		}
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", slog.Int("imported", report.Imported), sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", slog.Int("imported", report.Imported), sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to read import", slog.Int("imported", report.Imported), sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("failed to read request"))
+			return
+		}
+
+		log.Info("urls imported",
+			slog.Int("imported", report.Imported),
+			slog.Int("overwritten", report.Overwritten),
+			slog.Int("skipped", report.Skipped),
+			slog.Int("failed", report.Failed),
+		)
+
+		render.JSON(writer, request, ImportResponse{
+			Response: resp.OK(),
+			Report:   report,
+		})
+	}
+}
+
+func formatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType == "text/csv" {
+		return linktransfer.FormatCSV
+	}
+
+	return linktransfer.FormatJSONL
+}
+
+// trackingWriter запоминает, начал ли обработчик писать тело ответа.
+type trackingWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+
+	return t.w.Write(p)
+}
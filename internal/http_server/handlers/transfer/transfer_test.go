@@ -0,0 +1,307 @@
+package transfer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/transfer"
+	"url-shortener/internal/http_server/handlers/transfer/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/storage"
+)
+
+func TestExportHandler(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name                string
+		query               string
+		admin               bool
+		callsStorage        bool
+		mockError           error
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "CSV by default",
+			callsStorage:        true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "alias,url,clicks,created_at,last_accessed_at,expires_at,owner\n" +
+				"test_alias,https://google.com,3,2026-01-02T03:04:05Z,,,team-a\n",
+		},
+		{
+			name:                "JSONL",
+			query:               "?format=jsonl",
+			callsStorage:        true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"alias":"test_alias","url":"https://google.com","clicks":3,` +
+				`"created_at":"2026-01-02T03:04:05Z","owner":"team-a"}` + "\n",
+		},
+		{
+			name:                "Admin exports all owners",
+			admin:               true,
+			callsStorage:        true,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "alias,url,clicks,created_at,last_accessed_at,expires_at,owner\n" +
+				"test_alias,https://google.com,3,2026-01-02T03:04:05Z,,,team-a\n" +
+				"foreign_alias,https://example.com,0,2026-01-02T03:04:05Z,,,team-b\n",
+		},
+		{
+			name:                "Invalid format",
+			query:               "?format=xml",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json",
+			expectedBody:        makeErrorBody("field format must be one of: csv, jsonl") + "\n",
+		},
+		{
+			name:                "Storage timeout",
+			query:               "?format=jsonl",
+			callsStorage:        true,
+			mockError:           storage.ErrQueryTimeout,
+			expectedStatus:      http.StatusGatewayTimeout,
+			expectedContentType: "application/json",
+			expectedBody:        makeErrorBody("storage timeout") + "\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlExporterMock := mocks.NewURLExporter(t)
+
+			identity := auth.Identity{User: "team-a", Role: auth.RoleUser}
+			if tc.admin {
+				identity.Role = auth.RoleAdmin
+			}
+
+			if tc.callsStorage {
+				// Фильтр по владельцу применяет хранилище: пользователь — только
+				// свои ссылки, администратор — все
+				urlExporterMock.On("ForEachURL", mock.Anything, identity.Owner(), mock.Anything).
+					Run(func(args mock.Arguments) {
+						if tc.mockError != nil {
+							return
+						}
+
+						owner := args.String(1)
+						fn := args.Get(2).(func(storage.URLStats) error)
+
+						for _, url := range []storage.URLStats{
+							{
+								Alias:     "test_alias",
+								URL:       "https://google.com",
+								Clicks:    3,
+								CreatedAt: createdAt,
+								Owner:     "team-a",
+							},
+							{
+								Alias:     "foreign_alias",
+								URL:       "https://example.com",
+								CreatedAt: createdAt,
+								Owner:     "team-b",
+							},
+						} {
+							if owner == "" || url.Owner == owner {
+								_ = fn(url)
+							}
+						}
+					}).
+					Return(tc.mockError).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/export"+tc.query, nil)
+			req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+			rr := httptest.NewRecorder()
+
+			transfer.Export(slogdiscard.NewDiscardLogger(), urlExporterMock, time.Minute).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Contains(t, rr.Header().Get("Content-Type"), tc.expectedContentType)
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+// Медленный клиент или зависший обход базы не держат выгрузку дольше timeout.
+func TestExportHandler_Timeout(t *testing.T) {
+	urlExporterMock := mocks.NewURLExporter(t)
+	urlExporterMock.On("ForEachURL", mock.Anything, "", mock.Anything).
+		Return(func(ctx context.Context, _ string, _ func(storage.URLStats) error) error {
+			<-ctx.Done()
+			return storage.ContextError(ctx, ctx.Err())
+		}).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "ops", Role: auth.RoleAdmin}))
+	rr := httptest.NewRecorder()
+
+	transfer.Export(slogdiscard.NewDiscardLogger(), urlExporterMock, 10*time.Millisecond).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusGatewayTimeout, rr.Code)
+}
+
+func TestImportHandler(t *testing.T) {
+	aliases, err := random.NewAliasGenerator(random.AliasOptions{})
+	require.NoError(t, err)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name           string
+		query          string
+		contentType    string
+		body           string
+		admin          bool
+		saveError      error
+		callsUpdate    bool
+		updateError    error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "CSV from content type",
+			contentType:    "text/csv",
+			body:           "url,alias\nhttps://google.com,test_alias\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","imported":1,"overwritten":0,"skipped":0,"failed":0}`,
+		},
+		{
+			name:           "Skip conflict",
+			query:          "?format=jsonl",
+			body:           `{"url": "https://google.com", "alias": "test_alias"}`,
+			saveError:      storage.ErrUrlExist,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","imported":0,"overwritten":0,"skipped":1,"failed":0,` +
+				`"rows":[{"line":1,"alias":"test_alias","action":"skipped"}]}`,
+		},
+		{
+			name:           "Overwrite conflict",
+			query:          "?format=jsonl&conflict=overwrite",
+			body:           `{"url": "https://google.com", "alias": "test_alias"}`,
+			saveError:      storage.ErrUrlExist,
+			callsUpdate:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","imported":0,"overwritten":1,"skipped":0,"failed":0,` +
+				`"rows":[{"line":1,"alias":"test_alias","action":"overwritten"}]}`,
+		},
+		{
+			name:           "Overwrite foreign url",
+			query:          "?format=jsonl&conflict=overwrite",
+			body:           `{"url": "https://google.com", "alias": "test_alias"}`,
+			saveError:      storage.ErrUrlExist,
+			callsUpdate:    true,
+			updateError:    storage.ErrUrlForbidden,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","imported":0,"overwritten":0,"skipped":0,"failed":1,` +
+				`"rows":[{"line":1,"alias":"test_alias","action":"failed","error":"access denied"}]}`,
+		},
+		{
+			name:           "Admin overwrites any url",
+			query:          "?format=jsonl&conflict=overwrite",
+			body:           `{"url": "https://google.com", "alias": "test_alias"}`,
+			admin:          true,
+			saveError:      storage.ErrUrlExist,
+			callsUpdate:    true,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","imported":0,"overwritten":1,"skipped":0,"failed":0,` +
+				`"rows":[{"line":1,"alias":"test_alias","action":"overwritten"}]}`,
+		},
+		{
+			name:           "Invalid conflict policy",
+			query:          "?conflict=merge",
+			body:           `{"url": "https://google.com", "alias": "test_alias"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody(`unknown conflict policy "merge"`),
+		},
+		{
+			name:           "CSV without url column",
+			query:          "?format=csv",
+			body:           "alias,target\ntest_alias,https://google.com\n",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("csv header must contain url column"),
+		},
+		{
+			name:           "Storage timeout",
+			query:          "?format=jsonl",
+			body:           `{"url": "https://google.com", "alias": "test_alias"}`,
+			saveError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlImporterMock := mocks.NewURLImporter(t)
+
+			identity := auth.Identity{User: "team-a", Role: auth.RoleUser}
+			scope := "team-a"
+			if tc.admin {
+				identity.Role = auth.RoleAdmin
+				scope = ""
+			}
+
+			if tc.expectedStatus != http.StatusBadRequest {
+				urlImporterMock.On("SaveURL", mock.Anything, "https://google.com", "test_alias", time.Time{}, "team-a").
+					Return(int64(1), tc.saveError).
+					Once()
+			}
+			if tc.callsUpdate {
+				urlImporterMock.On("UpdateURL", mock.Anything, "test_alias", mock.MatchedBy(func(u storage.URLUpdate) bool {
+					return u.URL == "https://google.com" && u.ExpiresAt != nil && u.ExpiresAt.IsZero()
+				}), scope).
+					Return(tc.updateError).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/import"+tc.query, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+
+			rr := httptest.NewRecorder()
+			transfer.Import(slogdiscard.NewDiscardLogger(), urlImporterMock, aliases).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestTransferHandlers_Unauthenticated(t *testing.T) {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	transfer.Export(slogdiscard.NewDiscardLogger(), mocks.NewURLExporter(t), time.Minute).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(""))
+	transfer.Import(slogdiscard.NewDiscardLogger(), mocks.NewURLImporter(t), nil).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -1,9 +1,11 @@
 package delete
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
+	"url-shortener/internal/http_server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/storage"
@@ -15,16 +17,17 @@ import (
 
 //go:generate go run github.com/vektra/mockery/v2@v2 --name=URLDeleter
 type URLDeleter interface {
-	DeleteURL(alias string) error
+	DeleteURL(ctx context.Context, alias string, owner string) error
 }
 
 func Delete(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		const op = "handlers.url.delete.Delete"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
 		)
 
 		alias := chi.URLParam(request, "alias")
@@ -37,13 +40,39 @@ func Delete(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 			return
 		}
 
-		err := urlDeleter.DeleteURL(alias)
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			log.Error("request is not authenticated")
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+
+		err := urlDeleter.DeleteURL(request.Context(), alias, identity.Owner())
 		if errors.Is(err, storage.ErrUrlNotFound) {
 			log.Info("alias not found", slog.String("alias", alias))
 			render.Status(request, http.StatusNotFound)
 			render.JSON(writer, request, resp.Error("alias not found"))
 			return
 		}
+		if errors.Is(err, storage.ErrUrlForbidden) {
+			log.Warn("alias belongs to another user", slog.String("alias", alias), slog.String("user", identity.User))
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+			return
+		}
 		if err != nil {
 			log.Error("failed to delete url", sl.Err(err))
 			render.Status(request, http.StatusInternalServerError)
@@ -11,10 +11,12 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	delHandler "url-shortener/internal/http_server/handlers/url/delete" // Используем псевдоним delHandler
 	"url-shortener/internal/http_server/handlers/url/delete/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
 	"url-shortener/internal/storage"
@@ -23,6 +25,8 @@ import (
 type testCase struct {
 	name           string
 	alias          string
+	identity       auth.Identity
+	owner          string
 	mockError      error
 	expectedStatus int
 	expectedBody   string
@@ -53,6 +57,21 @@ func TestDeleteHandler(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   makeOkBody(),
 		},
+		{
+			name:           "Admin deletes any link",
+			alias:          "foreign_alias",
+			identity:       auth.Identity{User: "ops", Role: auth.RoleAdmin},
+			owner:          "",
+			expectedStatus: http.StatusOK,
+			expectedBody:   makeOkBody(),
+		},
+		{
+			name:           "Link of another user",
+			alias:          "foreign_alias",
+			mockError:      storage.ErrUrlForbidden,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   makeErrorBody("access denied"),
+		},
 		{
 			name:           "Empty alias",
 			alias:          "",
@@ -73,16 +92,29 @@ func TestDeleteHandler(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   makeErrorBody("internal server error"),
 		},
+		{
+			name:           "Storage timeout",
+			alias:          "slow_alias",
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			urlDeleterMock := mocks.NewURLDeleter(t)
 
+			// По умолчанию ссылку удаляет обычный пользователь team-a
+			if tc.identity == (auth.Identity{}) {
+				tc.identity = auth.Identity{User: "team-a", Role: auth.RoleUser}
+				tc.owner = "team-a"
+			}
+
 			// Настраиваем мок только если это необходимо для кейса
 			// (т.е. если не ожидается ошибка из-за пустого алиаса до вызова Deleter)
 			if tc.alias != "" {
-				urlDeleterMock.On("DeleteURL", tc.alias).
+				urlDeleterMock.On("DeleteURL", mock.Anything, tc.alias, tc.owner).
 					Return(tc.mockError).
 					Maybe() // Используем Maybe, так как DeleteURL не всегда будет вызван
 			}
@@ -101,6 +133,7 @@ func TestDeleteHandler(t *testing.T) {
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("alias", tc.alias) // Передаем ожидаемый алиас в контекст
 			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(auth.WithIdentity(req.Context(), tc.identity))
 
 			rr := httptest.NewRecorder()
 			router := chi.NewRouter()
@@ -124,9 +157,9 @@ func TestDeleteHandler(t *testing.T) {
 
 			// Проверяем вызовы мока
 			if tc.alias != "" { // DeleteURL не должен вызываться для пустого алиаса
-				urlDeleterMock.AssertCalled(t, "DeleteURL", tc.alias)
+				urlDeleterMock.AssertCalled(t, "DeleteURL", mock.Anything, tc.alias, tc.owner)
 			} else {
-				urlDeleterMock.AssertNotCalled(t, "DeleteURL", tc.alias)
+				urlDeleterMock.AssertNotCalled(t, "DeleteURL", mock.Anything, tc.alias, tc.owner)
 			}
 		})
 	}
@@ -0,0 +1,161 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type URL struct {
+	Alias          string     `json:"alias"`
+	URL            string     `json:"url"`
+	Clicks         int64      `json:"clicks"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Owner          string     `json:"owner,omitempty"`
+}
+
+// Response — страница списка ссылок. Следующая страница запрашивается
+// с параметром cursor=next_cursor, на последней странице next_cursor пуст.
+type Response struct {
+	resp.Response
+	URLs       []URL  `json:"urls"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=URLLister
+type URLLister interface {
+	ListURLs(ctx context.Context, opts storage.ListOptions) (storage.URLPage, error)
+}
+
+// New возвращает список ссылок. Параметры запроса: sort=created|alias|clicks,
+// alias и domain — поиск подстроки, limit — размер страницы, cursor — продолжение выдачи.
+// Пользователь видит только свои ссылки, администратор — все или владельца из owner.
+func New(log *slog.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			log.Error("request is not authenticated")
+
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+
+		query := request.URL.Query()
+
+		opts := storage.ListOptions{
+			Sort:   query.Get("sort"),
+			Alias:  query.Get("alias"),
+			Domain: query.Get("domain"),
+			Owner:  identity.Owner(),
+			Cursor: query.Get("cursor"),
+		}
+
+		if identity.IsAdmin() {
+			opts.Owner = query.Get("owner")
+		}
+
+		switch opts.Sort {
+		case "", storage.ListSortCreated, storage.ListSortAlias, storage.ListSortClicks:
+		default:
+			log.Info("invalid sort", slog.String("sort", opts.Sort))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("field sort must be one of: created, alias, clicks"))
+			return
+		}
+
+		if raw := query.Get("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > storage.MaxListLimit {
+				log.Info("invalid limit", slog.String("limit", raw))
+
+				render.Status(request, http.StatusBadRequest)
+				render.JSON(writer, request, resp.Error(fmt.Sprintf("field limit must be between 1 and %d", storage.MaxListLimit)))
+				return
+			}
+
+			opts.Limit = limit
+		}
+
+		page, err := urlLister.ListURLs(request.Context(), opts)
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			log.Info("invalid cursor", sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("invalid cursor"))
+			return
+		}
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+
+			render.Status(request, http.StatusInternalServerError)
+			render.JSON(writer, request, resp.Error("internal server error"))
+			return
+		}
+
+		log.Info("listed urls", slog.Int("count", len(page.URLs)))
+
+		urls := make([]URL, 0, len(page.URLs))
+		for _, u := range page.URLs {
+			urls = append(urls, URL{
+				Alias:          u.Alias,
+				URL:            u.URL,
+				Clicks:         u.Clicks,
+				CreatedAt:      timePtr(u.CreatedAt),
+				LastAccessedAt: timePtr(u.LastAccessedAt),
+				ExpiresAt:      timePtr(u.ExpiresAt),
+				Owner:          u.Owner,
+			})
+		}
+
+		render.JSON(writer, request, Response{
+			Response:   resp.OK(),
+			URLs:       urls,
+			NextCursor: page.NextCursor,
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,154 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/url/list"
+	"url-shortener/internal/http_server/handlers/url/list/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name           string
+		query          string
+		identity       *auth.Identity
+		expectedOpts   *storage.ListOptions
+		mockPage       storage.URLPage
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:         "Defaults",
+			expectedOpts: &storage.ListOptions{},
+			mockPage: storage.URLPage{
+				URLs: []storage.URLStats{
+					{Alias: "b", URL: "https://google.com", Clicks: 3, CreatedAt: createdAt},
+				},
+				NextCursor: "next",
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","urls":[{"alias":"b","url":"https://google.com","clicks":3,` +
+				`"created_at":"2026-01-02T03:04:05Z"}],"next_cursor":"next"}`,
+		},
+		{
+			name:  "Filters and paging",
+			query: "?sort=clicks&alias=camp&domain=example.com&limit=10&cursor=abc",
+			expectedOpts: &storage.ListOptions{
+				Sort:   storage.ListSortClicks,
+				Alias:  "camp",
+				Domain: "example.com",
+				Limit:  10,
+				Cursor: "abc",
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","urls":[]}`,
+		},
+		{
+			name:         "User sees only own links",
+			query:        "?owner=team-b",
+			identity:     &auth.Identity{User: "team-a", Role: auth.RoleUser},
+			expectedOpts: &storage.ListOptions{Owner: "team-a"},
+			mockPage: storage.URLPage{
+				URLs: []storage.URLStats{
+					{Alias: "mine", URL: "https://google.com", CreatedAt: createdAt, Owner: "team-a"},
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","urls":[{"alias":"mine","url":"https://google.com","clicks":0,` +
+				`"created_at":"2026-01-02T03:04:05Z","owner":"team-a"}]}`,
+		},
+		{
+			name:           "Admin filters by owner",
+			query:          "?owner=team-b",
+			expectedOpts:   &storage.ListOptions{Owner: "team-b"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","urls":[]}`,
+		},
+		{
+			name:           "Invalid sort",
+			query:          "?sort=url",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field sort must be one of: created, alias, clicks"),
+		},
+		{
+			name:           "Invalid limit",
+			query:          "?limit=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field limit must be between 1 and 500"),
+		},
+		{
+			name:           "Limit too large",
+			query:          "?limit=501",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field limit must be between 1 and 500"),
+		},
+		{
+			name:           "Invalid cursor",
+			query:          "?cursor=garbage",
+			expectedOpts:   &storage.ListOptions{Cursor: "garbage"},
+			mockError:      storage.ErrInvalidCursor,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("invalid cursor"),
+		},
+		{
+			name:           "Storage timeout",
+			expectedOpts:   &storage.ListOptions{},
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+		{
+			name:           "Internal error",
+			expectedOpts:   &storage.ListOptions{},
+			mockError:      errors.New("internal storage error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlListerMock := mocks.NewURLLister(t)
+			if tc.expectedOpts != nil {
+				urlListerMock.On("ListURLs", mock.Anything, *tc.expectedOpts).
+					Return(tc.mockPage, tc.mockError).
+					Once()
+			}
+
+			identity := auth.Identity{User: "ops", Role: auth.RoleAdmin}
+			if tc.identity != nil {
+				identity = *tc.identity
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/urls"+tc.query, nil)
+			req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+
+			rr := httptest.NewRecorder()
+			list.New(slogdiscard.NewDiscardLogger(), urlListerMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package save
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Режимы пакетного сохранения.
+const (
+	// BatchAtomic сохраняет пачку целиком или не сохраняет ничего.
+	BatchAtomic = "atomic"
+	// BatchBestEffort сохраняет все корректные элементы, ошибки возвращаются по каждому элементу.
+	BatchBestEffort = "best_effort"
+)
+
+// batchAttempts — сколько раз пачка отправляется в хранилище, если
+// сгенерированные алиасы совпали с существующими.
+const batchAttempts = 3
+
+const errBatchRejected = "not saved: batch rejected"
+
+// maxBatchItemBytes — сколько байт тела приходится на один элемент пачки. Тело
+// запроса ограничено (maxSize+1)*maxBatchItemBytes, чтобы один огромный элемент
+// не обходил ограничение на число элементов.
+const maxBatchItemBytes = 64 << 10
+
+var errBatchTooLarge = errors.New("batch is too large")
+
+// BatchResponse — результат пакетного сохранения. Results[i] соответствует i-му
+// элементу запроса и имеет тот же вид, что и ответ /save.
+type BatchResponse struct {
+	resp.Response
+	Saved   int        `json:"saved"`
+	Results []Response `json:"results,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=BatchSaver
+type BatchSaver interface {
+	SaveURLs(ctx context.Context, urls []storage.NewURL, atomic bool) ([]storage.SaveResult, error)
+}
+
+// NewBatch сохраняет массив Request за одну транзакцию. Каждый элемент проверяется
+// так же, как в New. Режим задается параметром mode=atomic|best_effort, по умолчанию
+// используется defaultMode.
+func NewBatch(log *slog.Logger, batchSaver BatchSaver, aliases AliasGenerator, maxSize int, defaultMode string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.url.save.NewBatch"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			log.Error("request is not authenticated")
+
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+
+		mode := request.URL.Query().Get("mode")
+		if mode == "" {
+			mode = defaultMode
+		}
+		if mode != BatchAtomic && mode != BatchBestEffort {
+			log.Info("invalid batch mode", slog.String("mode", mode))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("field mode must be one of: atomic, best_effort"))
+			return
+		}
+
+		request.Body = http.MaxBytesReader(writer, request.Body, int64(maxSize+1)*maxBatchItemBytes)
+
+		reqs, err := decodeBatch(request.Body, maxSize)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("request body is empty"))
+			return
+		}
+		if errors.Is(err, errBatchTooLarge) {
+			log.Info("batch is too large", slog.Int("max_size", maxSize))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error(fmt.Sprintf("batch is too large: max %d items", maxSize)))
+			return
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			log.Info("request body is too large", slog.Int64("limit", maxBytesErr.Limit))
+
+			render.Status(request, http.StatusRequestEntityTooLarge)
+			render.JSON(writer, request, resp.Error("request body is too large"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("failed to decode request"))
+			return
+		}
+
+		if len(reqs) == 0 {
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("batch is empty"))
+			return
+		}
+
+		log.Info("batch decoded", slog.Int("size", len(reqs)), slog.String("mode", mode))
+
+		results := make([]Response, len(reqs))
+		items := make([]batchItem, 0, len(reqs))
+		validate := validator.New()
+		now := time.Now()
+
+		for i, req := range reqs {
+			if err = validate.Struct(req); err != nil {
+				var validateErr validator.ValidationErrors
+
+				if !errors.As(err, &validateErr) {
+					log.Error("unexpected error during validation", sl.Err(err))
+
+					render.Status(request, http.StatusInternalServerError)
+					render.JSON(writer, request, resp.Error("internal server error"))
+					return
+				}
+
+				results[i] = Response{Response: resp.ValidatorError(validateErr)}
+				continue
+			}
+
+			expiresAt, err := req.Expiration(now)
+			if err != nil {
+				results[i] = Response{Response: resp.Error(err.Error())}
+				continue
+			}
+
+			if err = req.CheckAlias(); err != nil {
+				results[i] = Response{Response: resp.Error(err.Error())}
+				continue
+			}
+
+			item := batchItem{
+				index:     i,
+				generated: req.Alias == "",
+				url:       storage.NewURL{URL: req.URL, Alias: req.Alias, ExpiresAt: expiresAt, Owner: identity.User},
+			}
+			if item.generated {
+				item.url.Alias = aliases.Generate()
+			}
+
+			items = append(items, item)
+		}
+
+		atomic := mode == BatchAtomic
+
+		if atomic && len(items) < len(reqs) {
+			log.Info("batch rejected: invalid items", slog.Int("invalid", len(reqs)-len(items)))
+
+			rejectBatch(writer, request, results)
+			return
+		}
+
+		saved, err := saveBatch(request.Context(), log, batchSaver, aliases, items, results, atomic)
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save batch", sl.Err(err))
+
+			render.Status(request, http.StatusInternalServerError)
+			render.JSON(writer, request, resp.Error("failed to add urls"))
+			return
+		}
+
+		// В режиме atomic пачка не пуста, поэтому ноль сохраненных означает откат
+		if atomic && saved == 0 {
+			log.Info("batch rejected: alias conflicts")
+
+			rejectBatch(writer, request, results)
+			return
+		}
+
+		log.Info("batch saved", slog.Int("saved", saved), slog.Int("failed", len(reqs)-saved))
+
+		render.JSON(writer, request, BatchResponse{
+			Response: resp.OK(),
+			Saved:    saved,
+			Results:  results,
+		})
+	}
+}
+
+type batchItem struct {
+	index     int
+	generated bool
+	url       storage.NewURL
+}
+
+// saveBatch отправляет элементы в хранилище и заполняет results. Элементы со
+// сгенерированным алиасом, который оказался занят, отправляются повторно с новым
+// алиасом: в режиме atomic — вместе со всей пачкой, иначе — только они.
+func saveBatch(
+	ctx context.Context,
+	log *slog.Logger,
+	batchSaver BatchSaver,
+	aliases AliasGenerator,
+	items []batchItem,
+	results []Response,
+	atomic bool,
+) (int, error) {
+	saved := 0
+
+	for attempt := 1; len(items) > 0; attempt++ {
+		urls := make([]storage.NewURL, len(items))
+		for i, item := range items {
+			urls[i] = item.url
+		}
+
+		stored, err := batchSaver.SaveURLs(ctx, urls, atomic)
+		if err != nil {
+			return 0, err
+		}
+
+		var retry []batchItem
+		failed := false
+
+		for i, item := range items {
+			switch {
+			case stored[i].Err == nil:
+				results[item.index] = okResponse(item.url)
+				saved++
+			case errors.Is(stored[i].Err, storage.ErrUrlExist) && item.generated && attempt < batchAttempts:
+				log.Warn("alias collision", slog.Int("attempt", attempt), slog.String("alias", item.url.Alias))
+
+				items[i].url.Alias = aliases.Generate()
+				retry = append(retry, items[i])
+			case errors.Is(stored[i].Err, storage.ErrUrlExist):
+				results[item.index] = Response{Response: resp.Error("url already exist")}
+				failed = true
+			default:
+				results[item.index] = Response{Response: resp.Error("failed to add url")}
+				failed = true
+			}
+		}
+
+		if !atomic {
+			items = retry
+			continue
+		}
+
+		// В режиме atomic любая ошибка откатила пачку целиком
+		if failed {
+			return 0, nil
+		}
+		if len(retry) == 0 {
+			return saved, nil
+		}
+
+		// Пачка откатилась только из-за сгенерированных алиасов: повторяем ее целиком
+		saved = 0
+	}
+
+	return saved, nil
+}
+
+// rejectBatch отвечает на отклоненную в режиме atomic пачку: у элементов без
+// собственной ошибки указывается, что они не сохранены из-за других.
+func rejectBatch(writer http.ResponseWriter, request *http.Request, results []Response) {
+	for i := range results {
+		if results[i].Status != resp.StatusError {
+			results[i] = Response{Response: resp.Error(errBatchRejected)}
+		}
+	}
+
+	render.Status(request, http.StatusUnprocessableEntity)
+	render.JSON(writer, request, BatchResponse{
+		Response: resp.Error("batch rejected"),
+		Results:  results,
+	})
+}
+
+func okResponse(u storage.NewURL) Response {
+	response := Response{
+		Response: resp.OK(),
+		Alias:    u.Alias,
+	}
+
+	if !u.ExpiresAt.IsZero() {
+		expiresAt := u.ExpiresAt
+		response.ExpiresAt = &expiresAt
+	}
+
+	return response
+}
+
+// decodeBatch читает JSON-массив Request поэлементно и прекращает чтение, как
+// только элементов становится больше maxSize, не дочитывая остаток тела.
+func decodeBatch(body io.Reader, maxSize int) ([]Request, error) {
+	dec := json.NewDecoder(body)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected JSON array, got %v", tok)
+	}
+
+	var reqs []Request
+	for dec.More() {
+		if len(reqs) == maxSize {
+			return nil, errBatchTooLarge
+		}
+
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+
+	// Закрывающая скобка: обрезанное после "[" тело не должно выглядеть пустым.
+	if _, err := dec.Token(); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return reqs, nil
+}
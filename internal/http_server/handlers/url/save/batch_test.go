@@ -0,0 +1,227 @@
+package save_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/url/save"
+	"url-shortener/internal/http_server/handlers/url/save/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/storage"
+)
+
+func TestBatchHandler(t *testing.T) {
+	type call struct {
+		atomic  bool
+		urls    int
+		results []storage.SaveResult
+		err     error
+	}
+
+	cases := []struct {
+		name           string
+		query          string
+		body           string
+		calls          []call
+		expectedStatus int
+		expectedSaved  int
+		expectedError  string
+		// expectedItems — ожидаемые ошибки по элементам, пустая строка означает успех
+		expectedItems []string
+	}{
+		{
+			name:  "Best effort with invalid and conflicting items",
+			query: "?mode=best_effort",
+			body: `[{"url": "https://google.com", "alias": "first"},
+				{"url": "not a url", "alias": "bad"},
+				{"url": "https://ya.ru", "alias": "taken"}]`,
+			calls: []call{{
+				urls:    2,
+				results: []storage.SaveResult{{ID: 1}, {Err: storage.ErrUrlExist}},
+			}},
+			expectedStatus: http.StatusOK,
+			expectedSaved:  1,
+			expectedItems:  []string{"", "field URL must be a valid url", "url already exist"},
+		},
+		{
+			name:           "Atomic success",
+			body:           `[{"url": "https://google.com", "alias": "first"}, {"url": "https://ya.ru", "ttl": "1h"}]`,
+			calls:          []call{{atomic: true, urls: 2, results: []storage.SaveResult{{ID: 1}, {ID: 2}}}},
+			expectedStatus: http.StatusOK,
+			expectedSaved:  2,
+			expectedItems:  []string{"", ""},
+		},
+		{
+			name:           "Atomic rejects invalid item",
+			body:           `[{"url": "https://google.com"}, {"alias": "no_url"}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "batch rejected",
+			expectedItems:  []string{"not saved: batch rejected", "field URL is a required field"},
+		},
+		{
+			name:           "Atomic rejects reserved alias",
+			body:           `[{"url": "https://google.com"}, {"url": "https://ya.ru", "alias": "healthz"}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "batch rejected",
+			expectedItems:  []string{"not saved: batch rejected", "field Alias is reserved for a service route"},
+		},
+		{
+			name: "Atomic rejects conflict",
+			body: `[{"url": "https://google.com", "alias": "first"}, {"url": "https://ya.ru", "alias": "taken"}]`,
+			calls: []call{{
+				atomic:  true,
+				urls:    2,
+				results: []storage.SaveResult{{}, {Err: storage.ErrUrlExist}},
+			}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "batch rejected",
+			expectedItems:  []string{"not saved: batch rejected", "url already exist"},
+		},
+		{
+			name:  "Generated alias collision is retried",
+			query: "?mode=best_effort",
+			body:  `[{"url": "https://google.com", "alias": "first"}, {"url": "https://ya.ru"}]`,
+			calls: []call{
+				{urls: 2, results: []storage.SaveResult{{ID: 1}, {Err: storage.ErrUrlExist}}},
+				{urls: 1, results: []storage.SaveResult{{ID: 2}}},
+			},
+			expectedStatus: http.StatusOK,
+			expectedSaved:  2,
+			expectedItems:  []string{"", ""},
+		},
+		{
+			name: "Atomic retries whole batch on generated alias collision",
+			body: `[{"url": "https://google.com", "alias": "first"}, {"url": "https://ya.ru"}]`,
+			calls: []call{
+				{atomic: true, urls: 2, results: []storage.SaveResult{{}, {Err: storage.ErrUrlExist}}},
+				{atomic: true, urls: 2, results: []storage.SaveResult{{ID: 1}, {ID: 2}}},
+			},
+			expectedStatus: http.StatusOK,
+			expectedSaved:  2,
+			expectedItems:  []string{"", ""},
+		},
+		{
+			name:           "Too large",
+			body:           `[{"url": "https://a.ru"}, {"url": "https://b.ru"}, {"url": "https://c.ru"}, {"url": "https://d.ru"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "batch is too large: max 3 items",
+		},
+		{
+			name:           "Too large stops before the rest",
+			body:           `[{"url": "https://a.ru"}, {"url": "https://b.ru"}, {"url": "https://c.ru"}, {"url": "https://d.ru"}, not json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "batch is too large: max 3 items",
+		},
+		{
+			name:           "Body too large",
+			body:           `[{"url": "https://a.ru/` + strings.Repeat("a", 4*64<<10) + `"}]`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedError:  "request body is too large",
+		},
+		{
+			name:           "Truncated array",
+			body:           `[{"url": "https://a.ru"},`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "failed to decode request",
+		},
+		{
+			name:           "Empty batch",
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "batch is empty",
+		},
+		{
+			name:           "Not an array",
+			body:           `{"url": "https://google.com"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "failed to decode request",
+		},
+		{
+			name:           "Invalid mode",
+			query:          "?mode=sometimes",
+			body:           `[{"url": "https://google.com"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "field mode must be one of: atomic, best_effort",
+		},
+		{
+			name:           "Storage timeout",
+			body:           `[{"url": "https://google.com"}]`,
+			calls:          []call{{atomic: true, urls: 1, err: storage.ErrQueryTimeout}},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedError:  "storage timeout",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			batchSaverMock := mocks.NewBatchSaver(t)
+
+			var aliases []string
+
+			for _, c := range tc.calls {
+				batchSaverMock.On("SaveURLs", mock.Anything, mock.MatchedBy(func(urls []storage.NewURL) bool {
+					for _, u := range urls {
+						if u.Owner != "team-a" {
+							return false
+						}
+					}
+
+					return len(urls) == c.urls
+				}), c.atomic).
+					Run(func(args mock.Arguments) {
+						for _, u := range args.Get(1).([]storage.NewURL) {
+							aliases = append(aliases, u.Alias)
+						}
+					}).
+					Return(c.results, c.err).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/save/batch"+tc.query, bytes.NewReader([]byte(tc.body)))
+			req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "team-a", Role: auth.RoleUser}))
+			rr := httptest.NewRecorder()
+
+			generator, err := random.NewAliasGenerator(random.AliasOptions{})
+			require.NoError(t, err)
+
+			save.NewBatch(slogdiscard.NewDiscardLogger(), batchSaverMock, generator, 3, save.BatchAtomic).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+
+			var body save.BatchResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			assert.Equal(t, tc.expectedError, body.Error)
+			assert.Equal(t, tc.expectedSaved, body.Saved)
+			require.Len(t, body.Results, len(tc.expectedItems))
+
+			for i, expected := range tc.expectedItems {
+				item := body.Results[i]
+				if expected != "" {
+					assert.Equal(t, resp.StatusError, item.Status, "item %d", i)
+					assert.Equal(t, expected, item.Error, "item %d", i)
+					continue
+				}
+
+				assert.Equal(t, resp.StatusOk, item.Status, "item %d", i)
+				assert.NotEmpty(t, item.Alias, "item %d", i)
+			}
+
+			// Повторная попытка должна идти с новым сгенерированным алиасом
+			if len(tc.calls) == 2 {
+				require.NotEqual(t, aliases[1], aliases[len(aliases)-1])
+			}
+		})
+	}
+}
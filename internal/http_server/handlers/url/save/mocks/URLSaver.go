@@ -2,7 +2,13 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
 
 // URLSaver is an autogenerated mock type for the URLSaver type
 type URLSaver struct {
@@ -17,9 +23,9 @@ func (_m *URLSaver) EXPECT() *URLSaver_Expecter {
 	return &URLSaver_Expecter{mock: &_m.Mock}
 }
 
-// SaveURL provides a mock function with given fields: urlToSave, alias
-func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(urlToSave, alias)
+// SaveURL provides a mock function with given fields: ctx, urlToSave, alias, expiresAt, owner
+func (_m *URLSaver) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	ret := _m.Called(ctx, urlToSave, alias, expiresAt, owner)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveURL")
@@ -27,17 +33,17 @@ func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, string) (int64, error)); ok {
+		return rf(ctx, urlToSave, alias, expiresAt, owner)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, string) int64); ok {
+		r0 = rf(ctx, urlToSave, alias, expiresAt, owner)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time, string) error); ok {
+		r1 = rf(ctx, urlToSave, alias, expiresAt, owner)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -51,15 +57,18 @@ type URLSaver_SaveURL_Call struct {
 }
 
 // SaveURL is a helper method to define mock.On call
+//   - ctx context.Context
 //   - urlToSave string
 //   - alias string
-func (_e *URLSaver_Expecter) SaveURL(urlToSave interface{}, alias interface{}) *URLSaver_SaveURL_Call {
-	return &URLSaver_SaveURL_Call{Call: _e.mock.On("SaveURL", urlToSave, alias)}
+//   - expiresAt time.Time
+//   - owner string
+func (_e *URLSaver_Expecter) SaveURL(ctx interface{}, urlToSave interface{}, alias interface{}, expiresAt interface{}, owner interface{}) *URLSaver_SaveURL_Call {
+	return &URLSaver_SaveURL_Call{Call: _e.mock.On("SaveURL", ctx, urlToSave, alias, expiresAt, owner)}
 }
 
-func (_c *URLSaver_SaveURL_Call) Run(run func(urlToSave string, alias string)) *URLSaver_SaveURL_Call {
+func (_c *URLSaver_SaveURL_Call) Run(run func(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string)) *URLSaver_SaveURL_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(time.Time), args[4].(string))
 	})
 	return _c
 }
@@ -69,7 +78,7 @@ func (_c *URLSaver_SaveURL_Call) Return(_a0 int64, _a1 error) *URLSaver_SaveURL_
 	return _c
 }
 
-func (_c *URLSaver_SaveURL_Call) RunAndReturn(run func(string, string) (int64, error)) *URLSaver_SaveURL_Call {
+func (_c *URLSaver_SaveURL_Call) RunAndReturn(run func(context.Context, string, string, time.Time, string) (int64, error)) *URLSaver_SaveURL_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -1,13 +1,16 @@
 package save
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+	"url-shortener/internal/http_server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
-	"url-shortener/internal/lib/random"
 	"url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -15,32 +18,106 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// Request — запрос на сохранение ссылки. Срок действия задается либо
+// абсолютным временем ExpiresAt, либо длительностью TTL ("24h", "90m").
 type Request struct {
-	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	URL       string     `json:"url" validate:"required,url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
 }
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
-const AliasLength = 6
+var (
+	ErrExpiryConflict = errors.New("only one of expires_at and ttl can be set")
+	ErrInvalidTTL     = errors.New("field TTL must be a positive duration")
+	ErrExpiresInPast  = errors.New("field ExpiresAt must be in the future")
+	ErrReservedAlias  = errors.New("field Alias is reserved for a service route")
+)
+
+// ReservedAliases — пути, которые роутер сопоставляет раньше GET /{alias}.
+// Ссылка с таким алиасом сохранилась бы, но перейти по ней было бы нельзя.
+var ReservedAliases = []string{"delete", "export", "healthz", "metrics", "readyz", "urls"}
+
+// CheckAlias возвращает ErrReservedAlias, если алиас совпадает с маршрутом
+// сервиса. Регистр не важен, как и при поиске ссылки.
+func (r Request) CheckAlias() error {
+	for _, reserved := range ReservedAliases {
+		if strings.EqualFold(r.Alias, reserved) {
+			return ErrReservedAlias
+		}
+	}
+
+	return nil
+}
+
+// Expiration возвращает момент истечения ссылки относительно now.
+// Нулевое время означает ссылку без срока действия.
+func (r Request) Expiration(now time.Time) (time.Time, error) {
+	switch {
+	case r.ExpiresAt != nil && r.TTL != "":
+		return time.Time{}, ErrExpiryConflict
+	case r.ExpiresAt != nil:
+		if !r.ExpiresAt.After(now) {
+			return time.Time{}, ErrExpiresInPast
+		}
+
+		return *r.ExpiresAt, nil
+	case r.TTL != "":
+		ttl, err := time.ParseDuration(r.TTL)
+		if err != nil || ttl <= 0 {
+			return time.Time{}, ErrInvalidTTL
+		}
+
+		return now.Add(ttl), nil
+	default:
+		return time.Time{}, nil
+	}
+}
 
 //go:generate go run github.com/vektra/mockery/v2@v2 --name=URLSaver --with-expecterf
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error)
+}
+
+// AliasGenerator выдает случайный алиас для ссылки, сохраняемой без своего.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=AliasGenerator
+type AliasGenerator interface {
+	Generate() string
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// CollisionRecorder учитывает случайные алиасы, которые оказались заняты.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=CollisionRecorder
+type CollisionRecorder interface {
+	RecordAliasCollision()
+}
+
+func New(log *slog.Logger, urlSaver URLSaver, aliases AliasGenerator, collisions CollisionRecorder) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		const op = "handlers.url.save.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
 		)
 
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			log.Error("request is not authenticated")
+
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+
 		var req Request
 
 		err := render.DecodeJSON(request.Body, &req)
@@ -73,21 +150,49 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			return
 		}
 
+		expiresAt, err := req.Expiration(time.Now())
+		if err != nil {
+			log.Error("invalid expiration", sl.Err(err))
+
+			render.JSON(writer, request, resp.Error(err.Error()))
+
+			return
+		}
+
+		if err = req.CheckAlias(); err != nil {
+			log.Error("reserved alias", slog.String("alias", req.Alias))
+
+			render.JSON(writer, request, resp.Error(err.Error()))
+
+			return
+		}
+
 		alias := req.Alias
 		if alias == "" {
-			alias = random.NewRandomString(AliasLength)
+			alias = aliases.Generate()
 		}
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
+		id, err := urlSaver.SaveURL(request.Context(), req.URL, alias, expiresAt, identity.User)
+		if errors.Is(err, storage.ErrUrlExist) && req.Alias != "" {
+			// Занятый алиас, заданный пользователем, не подменяется случайным
+			// и не считается коллизией генератора.
+			log.Info("alias already exist", slog.String("alias", alias), slog.Int("status_code", http.StatusConflict))
+
+			render.JSON(writer, request, resp.Error("url already exist"))
+
+			return
+		}
 		if errors.Is(err, storage.ErrUrlExist) {
 			maxAttempts := 2
 			for attempt := 1; attempt <= maxAttempts; attempt++ {
-				alias = random.NewRandomString(AliasLength)
-				id, err = urlSaver.SaveURL(req.URL, alias)
+				collisions.RecordAliasCollision()
+
+				alias = aliases.Generate()
+				id, err = urlSaver.SaveURL(request.Context(), req.URL, alias, expiresAt, identity.User)
 
 				if err == nil {
 					log.Info("url added", slog.Int64("id", id), slog.String("alias", alias))
-					responseOK(writer, request, alias)
+					responseOK(writer, request, alias, expiresAt)
 					return
 				}
 
@@ -104,6 +209,22 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 
 			return
 		}
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+
+			return
+		}
 		if err != nil {
 			log.Error("failed to add url", sl.Err(err))
 
@@ -114,13 +235,19 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 
 		log.Info("url added", slog.Int64("id", id))
 
-		responseOK(writer, request, alias)
+		responseOK(writer, request, alias, expiresAt)
 	}
 }
 
-func responseOK(writer http.ResponseWriter, request *http.Request, alias string) {
-	render.JSON(writer, request, Response{
+func responseOK(writer http.ResponseWriter, request *http.Request, alias string, expiresAt time.Time) {
+	response := Response{
 		Response: resp.OK(),
 		Alias:    alias,
-	})
+	}
+
+	if !expiresAt.IsZero() {
+		response.ExpiresAt = &expiresAt
+	}
+
+	render.JSON(writer, request, response)
 }
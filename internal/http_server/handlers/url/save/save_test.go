@@ -14,7 +14,9 @@ import (
 
 	"url-shortener/internal/http_server/handlers/url/save"
 	"url-shortener/internal/http_server/handlers/url/save/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -22,8 +24,10 @@ func TestSaveHandler(t *testing.T) {
 		name      string
 		alias     string
 		url       string
+		extra     string
 		respError string
 		mockError error
+		status    int
 	}{
 		{
 			name:  "Success",
@@ -47,6 +51,12 @@ func TestSaveHandler(t *testing.T) {
 			alias:     "some_alias",
 			respError: "field URL must be a valid url",
 		},
+		{
+			name:      "Reserved alias",
+			url:       "https://google.com",
+			alias:     "Metrics",
+			respError: "field Alias is reserved for a service route",
+		},
 		{
 			name:      "SaveURL Error",
 			alias:     "test_alias",
@@ -54,6 +64,54 @@ func TestSaveHandler(t *testing.T) {
 			respError: "failed to add url",
 			mockError: errors.New("unexpected error"),
 		},
+		{
+			name:  "With TTL",
+			alias: "ttl_alias",
+			url:   "https://google.com",
+			extra: `, "ttl": "24h"`,
+		},
+		{
+			name:  "With expires_at",
+			alias: "expires_alias",
+			url:   "https://google.com",
+			extra: `, "expires_at": "2999-01-01T00:00:00Z"`,
+		},
+		{
+			name:      "Invalid TTL",
+			alias:     "ttl_alias",
+			url:       "https://google.com",
+			extra:     `, "ttl": "tomorrow"`,
+			respError: save.ErrInvalidTTL.Error(),
+		},
+		{
+			name:      "Negative TTL",
+			alias:     "ttl_alias",
+			url:       "https://google.com",
+			extra:     `, "ttl": "-1h"`,
+			respError: save.ErrInvalidTTL.Error(),
+		},
+		{
+			name:      "Expires in past",
+			alias:     "expires_alias",
+			url:       "https://google.com",
+			extra:     `, "expires_at": "2000-01-01T00:00:00Z"`,
+			respError: save.ErrExpiresInPast.Error(),
+		},
+		{
+			name:      "Both TTL and expires_at",
+			alias:     "expires_alias",
+			url:       "https://google.com",
+			extra:     `, "ttl": "1h", "expires_at": "2999-01-01T00:00:00Z"`,
+			respError: save.ErrExpiryConflict.Error(),
+		},
+		{
+			name:      "SaveURL Timeout",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "storage timeout",
+			mockError: storage.ErrQueryTimeout,
+			status:    http.StatusGatewayTimeout,
+		},
 	}
 
 	for _, tc := range cases {
@@ -65,22 +123,33 @@ func TestSaveHandler(t *testing.T) {
 			urlSaverMock := mocks.NewURLSaver(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveURL", mock.Anything, tc.url, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time"), "team-a").
 					Return(int64(1), tc.mockError).
 					Once()
 			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			aliasesMock := mocks.NewAliasGenerator(t)
+			if tc.alias == "" && (tc.respError == "" || tc.mockError != nil) {
+				aliasesMock.On("Generate").Return("gen123").Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, aliasesMock, mocks.NewCollisionRecorder(t))
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"%s}`, tc.url, tc.alias, tc.extra)
 
 			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
+			req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "team-a", Role: auth.RoleUser}))
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			status := http.StatusOK
+			if tc.status != 0 {
+				status = tc.status
+			}
+
+			require.Equal(t, status, rr.Code)
 
 			body := rr.Body.String()
 
@@ -90,6 +159,69 @@ func TestSaveHandler(t *testing.T) {
 
 			require.Equal(t, tc.respError, resp.Error)
 
+			if tc.alias == "" && tc.respError == "" {
+				require.Equal(t, "gen123", resp.Alias)
+			}
 		})
 	}
 }
+
+func TestSaveHandler_Unauthenticated(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://google.com"}`)))
+	rr := httptest.NewRecorder()
+
+	save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, mocks.NewAliasGenerator(t), mocks.NewCollisionRecorder(t)).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.JSONEq(t, `{"status":"ERROR","error":"access denied"}`, rr.Body.String())
+}
+
+func TestSaveHandler_UserAliasTaken(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "promo", mock.AnythingOfType("time.Time"), "team-a").
+		Return(int64(0), storage.ErrUrlExist).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://google.com", "alias": "promo"}`)))
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "team-a", Role: auth.RoleUser}))
+	rr := httptest.NewRecorder()
+
+	// Ни генератор, ни счетчик коллизий не должны вызываться.
+	save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, mocks.NewAliasGenerator(t), mocks.NewCollisionRecorder(t)).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"status":"ERROR","error":"url already exist"}`, rr.Body.String())
+}
+
+func TestSaveHandler_AliasCollision(t *testing.T) {
+	aliasesMock := mocks.NewAliasGenerator(t)
+	aliasesMock.On("Generate").Return("taken").Once()
+	aliasesMock.On("Generate").Return("fresh").Once()
+
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "taken", mock.AnythingOfType("time.Time"), "team-a").
+		Return(int64(0), storage.ErrUrlExist).
+		Once()
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "fresh", mock.AnythingOfType("time.Time"), "team-a").
+		Return(int64(1), nil).
+		Once()
+
+	collisionsMock := mocks.NewCollisionRecorder(t)
+	collisionsMock.On("RecordAliasCollision").Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(`{"url": "https://google.com"}`)))
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "team-a", Role: auth.RoleUser}))
+	rr := httptest.NewRecorder()
+
+	save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, aliasesMock, collisionsMock).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp save.Response
+
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Empty(t, resp.Error)
+	require.Equal(t, "fresh", resp.Alias)
+}
@@ -0,0 +1,208 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"url-shortener/internal/http_server/handlers/url/save"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// Request — запрос на изменение ссылки. Поля и правила проверки те же, что у
+// save.Request: url обязателен, срок действия задается через expires_at или ttl.
+// Если срок не передан, он остается прежним; ClearExpiry снимает его.
+type Request struct {
+	save.Request
+	ClearExpiry bool `json:"clear_expiry,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Alias     string     `json:"alias,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+var (
+	ErrAliasMismatch       = errors.New("alias can't be changed")
+	ErrClearExpiryConflict = errors.New("clear_expiry can't be combined with expires_at or ttl")
+)
+
+// Update возвращает изменения для хранилища относительно now.
+func (r Request) Update(now time.Time) (storage.URLUpdate, error) {
+	update := storage.URLUpdate{URL: r.URL}
+
+	if r.ClearExpiry {
+		if r.ExpiresAt != nil || r.TTL != "" {
+			return storage.URLUpdate{}, ErrClearExpiryConflict
+		}
+
+		update.ExpiresAt = &time.Time{}
+
+		return update, nil
+	}
+
+	expiresAt, err := r.Expiration(now)
+	if err != nil {
+		return storage.URLUpdate{}, err
+	}
+
+	if !expiresAt.IsZero() {
+		update.ExpiresAt = &expiresAt
+	}
+
+	return update, nil
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=URLUpdater
+type URLUpdater interface {
+	UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error
+}
+
+func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(request.Context())),
+			sl.TraceID(request.Context()),
+		)
+
+		alias := chi.URLParam(request, "alias")
+		if alias == "" {
+			log.Error("alias is empty")
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("invalid request"))
+			return
+		}
+
+		identity, ok := auth.FromContext(request.Context())
+		if !ok {
+			log.Error("request is not authenticated")
+
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(request.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("request body is empty"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		if err = validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+
+			if errors.As(err, &validateErr) {
+				log.Error("invalid request", sl.Err(err))
+
+				render.Status(request, http.StatusBadRequest)
+				render.JSON(writer, request, resp.ValidatorError(validateErr))
+			} else {
+				log.Error("unexpected error during validation", sl.Err(err))
+
+				render.Status(request, http.StatusInternalServerError)
+				render.JSON(writer, request, resp.Error("internal server error"))
+			}
+
+			return
+		}
+
+		if req.Alias != "" && !strings.EqualFold(req.Alias, alias) {
+			log.Error("alias mismatch", slog.String("alias", alias), slog.String("body_alias", req.Alias))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error(ErrAliasMismatch.Error()))
+			return
+		}
+
+		update, err := req.Update(time.Now())
+		if err != nil {
+			log.Error("invalid expiration", sl.Err(err))
+
+			render.Status(request, http.StatusBadRequest)
+			render.JSON(writer, request, resp.Error(err.Error()))
+			return
+		}
+
+		err = urlUpdater.UpdateURL(request.Context(), alias, update, identity.Owner())
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("alias not found", slog.String("alias", alias))
+
+			render.Status(request, http.StatusNotFound)
+			render.JSON(writer, request, resp.Error("alias not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrUrlForbidden) {
+			log.Warn("alias belongs to another user", slog.String("alias", alias), slog.String("user", identity.User))
+
+			render.Status(request, http.StatusForbidden)
+			render.JSON(writer, request, resp.Error("access denied"))
+			return
+		}
+		if errors.Is(err, storage.ErrQueryTimeout) {
+			log.Error("storage timeout", sl.Err(err))
+
+			render.Status(request, http.StatusGatewayTimeout)
+			render.JSON(writer, request, resp.Error("storage timeout"))
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Warn("request canceled", sl.Err(err))
+
+			render.Status(request, http.StatusServiceUnavailable)
+			render.JSON(writer, request, resp.Error("request canceled"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+
+			render.Status(request, http.StatusInternalServerError)
+			render.JSON(writer, request, resp.Error("internal server error"))
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias))
+
+		response := Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			URL:      update.URL,
+		}
+
+		if update.ExpiresAt != nil && !update.ExpiresAt.IsZero() {
+			response.ExpiresAt = update.ExpiresAt
+		}
+
+		render.JSON(writer, request, response)
+	}
+}
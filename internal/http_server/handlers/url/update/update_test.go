@@ -0,0 +1,194 @@
+package update_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/handlers/url/update"
+	"url-shortener/internal/http_server/handlers/url/update/mocks"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name           string
+		alias          string
+		body           string
+		callsStorage   bool
+		matchUpdate    func(storage.URLUpdate) bool
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:         "Retarget keeps expiry",
+			alias:        "test_alias",
+			body:         `{"url": "https://google.com"}`,
+			callsStorage: true,
+			matchUpdate: func(u storage.URLUpdate) bool {
+				return u.URL == "https://google.com" && u.ExpiresAt == nil
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"test_alias","url":"https://google.com"}`,
+		},
+		{
+			name:         "Set expires_at",
+			alias:        "test_alias",
+			body:         `{"url": "https://google.com", "expires_at": "` + expiresAt.Format(time.RFC3339) + `"}`,
+			callsStorage: true,
+			matchUpdate: func(u storage.URLUpdate) bool {
+				return u.ExpiresAt != nil && u.ExpiresAt.Equal(expiresAt)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"OK","alias":"test_alias","url":"https://google.com",` +
+				`"expires_at":"` + expiresAt.Format(time.RFC3339) + `"}`,
+		},
+		{
+			name:         "Clear expiry",
+			alias:        "test_alias",
+			body:         `{"url": "https://google.com", "clear_expiry": true}`,
+			callsStorage: true,
+			matchUpdate: func(u storage.URLUpdate) bool {
+				return u.ExpiresAt != nil && u.ExpiresAt.IsZero()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"test_alias","url":"https://google.com"}`,
+		},
+		{
+			name:           "Same alias in body",
+			alias:          "test_alias",
+			body:           `{"url": "https://google.com", "alias": "TEST_ALIAS"}`,
+			callsStorage:   true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","alias":"test_alias","url":"https://google.com"}`,
+		},
+		{
+			name:           "Empty body",
+			alias:          "test_alias",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("request body is empty"),
+		},
+		{
+			name:           "Missing URL",
+			alias:          "test_alias",
+			body:           `{"ttl": "1h"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field URL is a required field"),
+		},
+		{
+			name:           "Invalid URL",
+			alias:          "test_alias",
+			body:           `{"url": "some invalid URL"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field URL must be a valid url"),
+		},
+		{
+			name:           "Invalid TTL",
+			alias:          "test_alias",
+			body:           `{"url": "https://google.com", "ttl": "-1h"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("field TTL must be a positive duration"),
+		},
+		{
+			name:           "Clear expiry with TTL",
+			alias:          "test_alias",
+			body:           `{"url": "https://google.com", "ttl": "1h", "clear_expiry": true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody(update.ErrClearExpiryConflict.Error()),
+		},
+		{
+			name:           "Alias change",
+			alias:          "test_alias",
+			body:           `{"url": "https://google.com", "alias": "other"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   makeErrorBody("alias can't be changed"),
+		},
+		{
+			name:           "Alias not found",
+			alias:          "missing",
+			body:           `{"url": "https://google.com"}`,
+			callsStorage:   true,
+			mockError:      storage.ErrUrlNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   makeErrorBody("alias not found"),
+		},
+		{
+			name:           "Link of another user",
+			alias:          "foreign",
+			body:           `{"url": "https://google.com"}`,
+			callsStorage:   true,
+			mockError:      storage.ErrUrlForbidden,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   makeErrorBody("access denied"),
+		},
+		{
+			name:           "Storage timeout",
+			alias:          "slow",
+			body:           `{"url": "https://google.com"}`,
+			callsStorage:   true,
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+		{
+			name:           "Internal error",
+			alias:          "broken",
+			body:           `{"url": "https://google.com"}`,
+			callsStorage:   true,
+			mockError:      errors.New("internal storage error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+
+			if tc.callsStorage {
+				var matcher any = mock.Anything
+				if tc.matchUpdate != nil {
+					matcher = mock.MatchedBy(tc.matchUpdate)
+				}
+
+				urlUpdaterMock.On("UpdateURL", mock.Anything, tc.alias, matcher, "team-a").
+					Return(tc.mockError).
+					Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPatch, "/url/"+tc.alias, bytes.NewReader([]byte(tc.body)))
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", tc.alias)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{User: "team-a", Role: auth.RoleUser}))
+
+			rr := httptest.NewRecorder()
+			update.New(slogdiscard.NewDiscardLogger(), urlUpdaterMock).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+}
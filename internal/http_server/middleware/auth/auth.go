@@ -0,0 +1,444 @@
+// Package auth проверяет учетные данные запроса и кладет в его контекст
+// личность вызывающего: пользователя Basic Auth, владельца ключа API или
+// субъекта токена JWT.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwtauth"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Способы аутентификации.
+const (
+	MethodBasic  = "basic"
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Роли. Администратор управляет ключами и всеми ссылками, пользователь — только своими.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Identity — кто выполняет запрос. KeyID заполнен только для MethodAPIKey.
+type Identity struct {
+	User   string
+	Method string
+	Role   string
+	KeyID  int64
+}
+
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+// Owner возвращает владельца, которым ограничены операции со ссылками:
+// имя пользователя или пустую строку (без ограничения) для администратора.
+func (i Identity) Owner() string {
+	if i.IsAdmin() {
+		return ""
+	}
+
+	return i.User
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=KeyFinder
+type KeyFinder interface {
+	FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error)
+}
+
+// User — учетная запись Basic Auth из конфига.
+type User struct {
+	Name string
+	// PasswordHash — хэш bcrypt или argon2id, см. lib/password.
+	PasswordHash string
+	// Password — пароль в открытом виде из устаревших http_server.user и
+	// http_server.password. Используется, только если PasswordHash пуст.
+	Password string
+	// Role — роль пользователя, пустая означает RoleUser: забытая в конфиге
+	// роль не должна давать прав администратора.
+	Role string
+}
+
+// TokenVerifier проверяет токены JWT. Ошибка с jwtauth.ErrInvalidToken
+// означает отказ в доступе, остальные — сбой проверки.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2 --name=TokenVerifier
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (jwtauth.Subject, error)
+}
+
+type Options struct {
+	Realm string
+	// Users — учетные записи Basic Auth.
+	Users []User
+	// Keys ищет ключи из заголовка "Authorization: Bearer". nil отключает ключи.
+	Keys KeyFinder
+	// Tokens проверяет токены JWT из того же заголовка. Токен отличается от
+	// ключа API по виду: три части через точку. nil отключает JWT.
+	Tokens TokenVerifier
+}
+
+// New возвращает middleware, которое пропускает только запросы с верными
+// учетными данными. Остальным отвечает 401 с заголовком WWW-Authenticate.
+// Неудачные попытки входа пишутся в лог с именем пользователя и адресом клиента.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	users := newBasicUsers(opts.Users)
+
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		challenge := fmt.Sprintf(`Basic realm=%q`, opts.Realm)
+		if opts.Keys != nil || opts.Tokens != nil {
+			challenge = fmt.Sprintf(`Bearer realm=%q, %s`, opts.Realm, challenge)
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.New"
+
+			log := log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.TraceID(r.Context()),
+			)
+
+			identity, err := authenticate(r, opts, users)
+			if err != nil {
+				var failure *authFailure
+				if errors.As(err, &failure) {
+					log.Warn("authentication failed",
+						slog.String("method", failure.method),
+						slog.String("user", failure.user),
+						slog.String("reason", failure.reason),
+						slog.String("remote_addr", r.RemoteAddr),
+					)
+				}
+				if errors.Is(err, errUnauthorized) {
+					w.Header().Add("WWW-Authenticate", challenge)
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, response.Error("unauthorized"))
+
+					return
+				}
+
+				log.Error("failed to authenticate", sl.Err(err))
+
+				switch {
+				case errors.Is(err, storage.ErrQueryTimeout):
+					render.Status(r, http.StatusGatewayTimeout)
+					render.JSON(w, r, response.Error("storage timeout"))
+				case errors.Is(err, context.Canceled):
+					render.Status(r, http.StatusServiceUnavailable)
+					render.JSON(w, r, response.Error("request canceled"))
+				default:
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, response.Error("internal server error"))
+				}
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// authFailure — отклоненные учетные данные. Запрос без них сюда не попадает:
+// это не попытка входа, а первый запрос браузера.
+type authFailure struct {
+	method string
+	user   string
+	reason string
+}
+
+func (f *authFailure) Error() string {
+	return fmt.Sprintf("%s auth failed for %q: %s", f.method, f.user, f.reason)
+}
+
+func (f *authFailure) Unwrap() error {
+	return errUnauthorized
+}
+
+func authenticate(r *http.Request, opts Options, users *basicUsers) (Identity, error) {
+	token, hasToken := bearerToken(r)
+
+	if hasToken && opts.Tokens != nil && strings.Count(token, ".") == 2 {
+		subject, err := opts.Tokens.VerifyToken(r.Context(), token)
+		if errors.Is(err, jwtauth.ErrInvalidToken) {
+			return Identity{}, &authFailure{method: MethodJWT, reason: err.Error()}
+		}
+		if err != nil {
+			return Identity{}, err
+		}
+
+		role := RoleUser
+		if subject.Admin {
+			role = RoleAdmin
+		}
+
+		return Identity{User: subject.User, Method: MethodJWT, Role: role}, nil
+	}
+
+	if hasToken && opts.Keys != nil {
+		key, err := opts.Keys.FindAPIKey(r.Context(), apikey.Hash(token))
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return Identity{}, &authFailure{method: MethodAPIKey, user: keyPrefix(token), reason: "unknown or revoked key"}
+		}
+		if err != nil {
+			return Identity{}, err
+		}
+
+		role := key.Role
+		if role == "" {
+			role = RoleUser
+		}
+
+		return Identity{User: key.User, Method: MethodAPIKey, Role: role, KeyID: key.ID}, nil
+	}
+
+	name, pass, ok := r.BasicAuth()
+	if !ok {
+		return Identity{}, errUnauthorized
+	}
+
+	user, err := users.check(name, pass)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	role := user.Role
+	if role == "" {
+		role = RoleUser
+	}
+
+	return Identity{User: user.Name, Method: MethodBasic, Role: role}, nil
+}
+
+// CheckUsers проверяет учетные записи до запуска сервера: имена не пустые и
+// не повторяются, роли известны, хэши паролей разбираются.
+func CheckUsers(users []User) error {
+	const op = "middleware.auth.CheckUsers"
+
+	seen := make(map[string]bool, len(users))
+
+	for _, user := range users {
+		if user.Name == "" {
+			return fmt.Errorf("%s: user without name", op)
+		}
+		if seen[user.Name] {
+			return fmt.Errorf("%s: duplicate user %q", op, user.Name)
+		}
+		seen[user.Name] = true
+
+		if user.Role != "" && user.Role != RoleUser && user.Role != RoleAdmin {
+			return fmt.Errorf("%s: user %q: unknown role %q", op, user.Name, user.Role)
+		}
+
+		if user.PasswordHash == "" {
+			if user.Password == "" {
+				return fmt.Errorf("%s: user %q: empty password", op, user.Name)
+			}
+
+			continue
+		}
+
+		if err := password.Validate(user.PasswordHash); err != nil {
+			return fmt.Errorf("%s: user %q: %w", op, user.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// keyPrefix возвращает открытую часть ключа для лога, как в списке ключей.
+func keyPrefix(token string) string {
+	if len(token) < apikey.PrefixLength || !strings.HasPrefix(token, apikey.Scheme) {
+		return ""
+	}
+
+	return token[:apikey.PrefixLength]
+}
+
+// dummyHash сверяется с паролем неизвестного пользователя, чтобы ответ
+// занимал столько же времени, сколько и для существующего.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := password.Hash("dummy password", password.AlgoBcrypt)
+	return hash
+})
+
+// basicUsers проверяет пароли Basic Auth. Медленный хэш считается только при
+// первом успешном входе, затем запоминается SHA-256 пароля, и следующие
+// запросы того же пользователя сверяются с ним.
+type basicUsers struct {
+	users map[string]User
+
+	mu       sync.RWMutex
+	verified map[string][sha256.Size]byte
+}
+
+func newBasicUsers(users []User) *basicUsers {
+	b := &basicUsers{
+		users:    make(map[string]User, len(users)),
+		verified: make(map[string][sha256.Size]byte),
+	}
+
+	for _, user := range users {
+		b.users[user.Name] = user
+
+		if user.PasswordHash == "" {
+			b.verified[user.Name] = sha256.Sum256([]byte(user.Password))
+		}
+	}
+
+	return b
+}
+
+func (b *basicUsers) check(name, pass string) (User, error) {
+	digest := sha256.Sum256([]byte(pass))
+
+	user, exists := b.users[name]
+	if !exists {
+		_, _ = password.Verify(dummyHash(), pass)
+
+		return User{}, &authFailure{method: MethodBasic, user: name, reason: "unknown user"}
+	}
+
+	b.mu.RLock()
+	known, cached := b.verified[name]
+	b.mu.RUnlock()
+
+	if cached && subtle.ConstantTimeCompare(digest[:], known[:]) == 1 {
+		return user, nil
+	}
+	if user.PasswordHash == "" {
+		return User{}, &authFailure{method: MethodBasic, user: name, reason: "invalid password"}
+	}
+
+	ok, err := password.Verify(user.PasswordHash, pass)
+	if err != nil {
+		return User{}, fmt.Errorf("user %q: %w", name, err)
+	}
+	if !ok {
+		return User{}, &authFailure{method: MethodBasic, user: name, reason: "invalid password"}
+	}
+
+	b.mu.Lock()
+	b.verified[name] = digest
+	b.mu.Unlock()
+
+	return user, nil
+}
+
+// RequireRole пропускает только запросы с ролью role, остальным отвечает 403.
+// Ставится после New.
+func RequireRole(log *slog.Logger, role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.RequireRole"
+
+			identity, ok := FromContext(r.Context())
+			if !ok || identity.Role != role {
+				log.Warn("access denied",
+					slog.String("op", op),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					sl.TraceID(r.Context()),
+					slog.String("user", identity.User),
+					slog.String("required_role", role),
+				)
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("access denied"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+type ctxKey int
+
+const (
+	identityKey ctxKey = iota
+	slotKey
+)
+
+// slot хранит личность для middleware, которые стоят в цепочке раньше auth
+// и не видят контекст, переданный дальше.
+type slot struct {
+	identity Identity
+	ok       bool
+}
+
+// Track готовит контекст запроса так, чтобы личность, установленная глубже
+// по цепочке, была видна через FromContext и в этом контексте. Так ее
+// узнает логгер, который оборачивает весь роутер.
+func Track(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(slotKey).(*slot); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, slotKey, &slot{})
+}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	if s, ok := ctx.Value(slotKey).(*slot); ok {
+		s.identity, s.ok = identity, true
+	}
+
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// FromContext возвращает личность вызывающего, если запрос прошел аутентификацию.
+func FromContext(ctx context.Context) (Identity, bool) {
+	if identity, ok := ctx.Value(identityKey).(Identity); ok {
+		return identity, true
+	}
+
+	if s, ok := ctx.Value(slotKey).(*slot); ok && s.ok {
+		return s.identity, true
+	}
+
+	return Identity{}, false
+}
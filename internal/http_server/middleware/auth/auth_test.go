@@ -0,0 +1,417 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/middleware/auth"
+	"url-shortener/internal/http_server/middleware/auth/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/jwtauth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/storage"
+)
+
+// testUsers — пользователь со старым паролем в открытом виде и пользователи с хэшами.
+func testUsers(t *testing.T) []auth.User {
+	t.Helper()
+
+	bcryptHash, err := password.Hash("s3cret", password.AlgoBcrypt)
+	require.NoError(t, err)
+
+	argonHash, err := password.Hash("0ps", password.AlgoArgon2id)
+	require.NoError(t, err)
+
+	return []auth.User{
+		{Name: "us", Password: "pass"},
+		{Name: "team-a", PasswordHash: bcryptHash, Role: auth.RoleUser},
+		{Name: "ops", PasswordHash: argonHash, Role: auth.RoleAdmin},
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	const key = "usk_valid"
+
+	users := testUsers(t)
+
+	makeErrorBody := func(msg string) string {
+		jsonBody, _ := json.Marshal(resp.Error(msg))
+		return string(jsonBody)
+	}
+
+	cases := []struct {
+		name             string
+		user, password   string
+		authorization    string
+		mockKey          storage.APIKey
+		mockError        error
+		expectedStatus   int
+		expectedIdentity auth.Identity
+		expectedBody     string
+	}{
+		{
+			name:             "Basic without role",
+			user:             "us",
+			password:         "pass",
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "us", Method: auth.MethodBasic, Role: auth.RoleUser},
+		},
+		{
+			name:             "Bcrypt user",
+			user:             "team-a",
+			password:         "s3cret",
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "team-a", Method: auth.MethodBasic, Role: auth.RoleUser},
+		},
+		{
+			name:             "Argon2id user",
+			user:             "ops",
+			password:         "0ps",
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "ops", Method: auth.MethodBasic, Role: auth.RoleAdmin},
+		},
+		{
+			name:           "Wrong hashed password",
+			user:           "team-a",
+			password:       "pass",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   makeErrorBody("unauthorized"),
+		},
+		{
+			name:           "Wrong password",
+			user:           "us",
+			password:       "wrong",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   makeErrorBody("unauthorized"),
+		},
+		{
+			name:           "Unknown user",
+			user:           "them",
+			password:       "pass",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   makeErrorBody("unauthorized"),
+		},
+		{
+			name:           "No credentials",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   makeErrorBody("unauthorized"),
+		},
+		{
+			name:             "API key",
+			authorization:    "Bearer " + key,
+			mockKey:          storage.APIKey{ID: 7, User: "team-a", Role: auth.RoleUser},
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "team-a", Method: auth.MethodAPIKey, Role: auth.RoleUser, KeyID: 7},
+		},
+		{
+			name:             "Admin API key",
+			authorization:    "Bearer " + key,
+			mockKey:          storage.APIKey{ID: 8, User: "ops", Role: auth.RoleAdmin},
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "ops", Method: auth.MethodAPIKey, Role: auth.RoleAdmin, KeyID: 8},
+		},
+		{
+			name:           "Unknown or revoked key",
+			authorization:  "bearer " + key,
+			mockError:      storage.ErrAPIKeyNotFound,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   makeErrorBody("unauthorized"),
+		},
+		{
+			name:           "Storage timeout",
+			authorization:  "Bearer " + key,
+			mockError:      storage.ErrQueryTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   makeErrorBody("storage timeout"),
+		},
+		{
+			name:           "Internal error",
+			authorization:  "Bearer " + key,
+			mockError:      errors.New("internal storage error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   makeErrorBody("internal server error"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keyFinderMock := mocks.NewKeyFinder(t)
+
+			if tc.authorization != "" {
+				keyFinderMock.On("FindAPIKey", mock.Anything, apikey.Hash(key)).
+					Return(tc.mockKey, tc.mockError).
+					Once()
+			}
+
+			var identity auth.Identity
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ = auth.FromContext(r.Context())
+			})
+
+			handler := auth.New(slogdiscard.NewDiscardLogger(), auth.Options{
+				Realm: "test",
+				Users: users,
+				Keys:  keyFinderMock,
+			})(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.password)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedIdentity, identity)
+
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+			}
+			if tc.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="test", Basic realm="test"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_KeysDisabled(t *testing.T) {
+	handler := auth.New(slogdiscard.NewDiscardLogger(), auth.Options{
+		Realm: "admin",
+		Users: []auth.User{{Name: "us", Password: "pass"}},
+	})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer usk_valid")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, `Basic realm="admin"`, rr.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuthMiddleware_JWT(t *testing.T) {
+	const token = "eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJzdmMifQ.c2ln"
+
+	cases := []struct {
+		name             string
+		authorization    string
+		callsVerifier    bool
+		callsKeys        bool
+		mockSubject      jwtauth.Subject
+		mockError        error
+		expectedStatus   int
+		expectedIdentity auth.Identity
+	}{
+		{
+			name:             "User token",
+			authorization:    "Bearer " + token,
+			callsVerifier:    true,
+			mockSubject:      jwtauth.Subject{User: "svc-reports"},
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "svc-reports", Method: auth.MethodJWT, Role: auth.RoleUser},
+		},
+		{
+			name:             "Admin token",
+			authorization:    "Bearer " + token,
+			callsVerifier:    true,
+			mockSubject:      jwtauth.Subject{User: "ops", Admin: true},
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: auth.Identity{User: "ops", Method: auth.MethodJWT, Role: auth.RoleAdmin},
+		},
+		{
+			name:           "Invalid token",
+			authorization:  "Bearer " + token,
+			callsVerifier:  true,
+			mockError:      fmt.Errorf("expired: %w", jwtauth.ErrInvalidToken),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Verifier failure",
+			authorization:  "Bearer " + token,
+			callsVerifier:  true,
+			mockError:      errors.New("unexpected"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "API key is not a token",
+			authorization:  "Bearer usk_valid",
+			callsKeys:      true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifierMock := mocks.NewTokenVerifier(t)
+			keyFinderMock := mocks.NewKeyFinder(t)
+
+			if tc.callsVerifier {
+				verifierMock.On("VerifyToken", mock.Anything, token).
+					Return(tc.mockSubject, tc.mockError).
+					Once()
+			}
+			if tc.callsKeys {
+				keyFinderMock.On("FindAPIKey", mock.Anything, apikey.Hash("usk_valid")).
+					Return(storage.APIKey{}, storage.ErrAPIKeyNotFound).
+					Once()
+			}
+
+			var identity auth.Identity
+
+			handler := auth.New(slogdiscard.NewDiscardLogger(), auth.Options{
+				Realm:  "test",
+				Keys:   keyFinderMock,
+				Tokens: verifierMock,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ = auth.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tc.authorization)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, tc.expectedIdentity, identity)
+		})
+	}
+}
+
+func TestAuthMiddleware_RepeatedLogin(t *testing.T) {
+	handler := auth.New(slogdiscard.NewDiscardLogger(), auth.Options{
+		Users: testUsers(t),
+	})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	// Второй вход сверяется с запомненным паролем, а смена пароля в запросе
+	// снова требует проверки по хэшу
+	for _, tc := range []struct {
+		password       string
+		expectedStatus int
+	}{
+		{"s3cret", http.StatusOK},
+		{"s3cret", http.StatusOK},
+		{"guess", http.StatusUnauthorized},
+		{"s3cret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("team-a", tc.password)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, tc.expectedStatus, rr.Code, "password %q", tc.password)
+	}
+}
+
+func TestAuthMiddleware_LogsFailures(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := auth.New(slog.New(slog.NewTextHandler(&buf, nil)), auth.Options{
+		Users: []auth.User{{Name: "us", Password: "pass"}},
+	})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Empty(t, buf.String(), "request without credentials is not a login attempt")
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.SetBasicAuth("us", "wrong")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, buf.String(), `msg="authentication failed"`)
+	assert.Contains(t, buf.String(), `user=us reason="invalid password" remote_addr=192.0.2.1:1234`)
+	assert.NotContains(t, buf.String(), "wrong", "password must not be logged")
+}
+
+func TestTrack(t *testing.T) {
+	var outer *http.Request
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Как логгер: оборачивает цепочку и читает личность после нее
+		outer = r.WithContext(auth.Track(r.Context()))
+
+		auth.New(slogdiscard.NewDiscardLogger(), auth.Options{
+			Users: []auth.User{{Name: "us", Password: "pass"}},
+		})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, outer)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("us", "pass")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	identity, ok := auth.FromContext(outer.Context())
+	require.True(t, ok)
+	assert.Equal(t, auth.Identity{User: "us", Method: auth.MethodBasic, Role: auth.RoleUser}, identity)
+}
+
+func TestRequireRole(t *testing.T) {
+	cases := []struct {
+		name           string
+		identity       *auth.Identity
+		expectedStatus int
+	}{
+		{
+			name:           "Admin",
+			identity:       &auth.Identity{User: "ops", Role: auth.RoleAdmin},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "User",
+			identity:       &auth.Identity{User: "team-a", Role: auth.RoleUser},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Anonymous",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := auth.RequireRole(slogdiscard.NewDiscardLogger(), auth.RoleAdmin)(
+				http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+			if tc.identity != nil {
+				req = req.WithContext(auth.WithIdentity(req.Context(), *tc.identity))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+
+			if tc.expectedStatus == http.StatusForbidden {
+				assert.JSONEq(t, `{"status":"ERROR","error":"access denied"}`, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestIdentityOwner(t *testing.T) {
+	assert.Equal(t, "team-a", auth.Identity{User: "team-a", Role: auth.RoleUser}.Owner())
+	assert.Empty(t, auth.Identity{User: "ops", Role: auth.RoleAdmin}.Owner())
+}
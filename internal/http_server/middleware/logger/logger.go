@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+	"url-shortener/internal/http_server/middleware/auth"
+	"url-shortener/internal/lib/logger/sl"
 )
 
 func New(log *slog.Logger) func(next http.Handler) http.Handler {
@@ -22,11 +24,25 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.TraceID(r.Context()),
 			)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			r = r.WithContext(auth.Track(r.Context()))
 
 			t1 := time.Now()
 			defer func() {
+				if identity, ok := auth.FromContext(r.Context()); ok {
+					entry = entry.With(
+						slog.String("user", identity.User),
+						slog.String("auth", identity.Method),
+						slog.String("role", identity.Role),
+					)
+
+					if identity.KeyID != 0 {
+						entry = entry.With(slog.Int64("key_id", identity.KeyID))
+					}
+				}
+
 				entry.Info("request completed",
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
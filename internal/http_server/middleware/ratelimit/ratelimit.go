@@ -0,0 +1,160 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket.
+// Корзина заводится на каждого пользователя, прошедшего аутентификацию, а
+// для анонимных запросов — на адрес клиента.
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+	"url-shortener/internal/http_server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/sl"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Limit — скорость пополнения корзины и ее емкость. Нулевой RPS отключает ограничение.
+type Limit struct {
+	RPS   float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.RPS > 0
+}
+
+type Options struct {
+	// Name — имя ограничения для логов: save, redirect, delete.
+	Name string
+	Limit
+	// TrustedProxies — прокси, чьим заголовкам X-Forwarded-For и X-Real-IP можно верить.
+	TrustedProxies []netip.Prefix
+}
+
+// New возвращает middleware, которое отвечает 429 с заголовком Retry-After,
+// когда корзина вызывающего пуста. Для пользователя middleware ставится после auth.
+// С выключенным ограничением запросы проходят без изменений.
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
+	if !opts.Enabled() {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	limiter := newLimiter(opts.Limit, time.Now)
+
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("limit", opts.Name),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + clientip.FromRequest(r, opts.TrustedProxies)
+			if identity, ok := auth.FromContext(r.Context()); ok {
+				key = "user:" + identity.User
+			}
+
+			allowed, retryAfter := limiter.allow(key)
+			if !allowed {
+				log.Warn("rate limit exceeded",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					sl.TraceID(r.Context()),
+					slog.String("key", key),
+				)
+
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("too many requests"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// sweepInterval — как часто выбрасывать полные корзины. Полная корзина
+// ничем не отличается от новой, поэтому ее удаление ничего не меняет для клиента.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type limiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+func newLimiter(limit Limit, now func() time.Time) *limiter {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = math.Max(1, math.Ceil(limit.RPS))
+	}
+
+	return &limiter{
+		rate:    limit.RPS,
+		burst:   burst,
+		now:     now,
+		buckets: make(map[string]*bucket),
+		sweptAt: now(),
+	}
+}
+
+// allow забирает из корзины key один токен. Если токена нет, возвращает,
+// через сколько он появится.
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if now.Sub(l.sweptAt) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+func (l *limiter) sweep(now time.Time) {
+	l.sweptAt = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http_server/middleware/auth"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	l := newLimiter(Limit{RPS: 2, Burst: 3}, func() time.Time { return now })
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("a")
+		require.True(t, ok, "burst request %d", i)
+	}
+
+	ok, retryAfter := l.allow("a")
+	require.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Другой ключ — своя корзина
+	ok, _ = l.allow("b")
+	assert.True(t, ok)
+
+	// За полсекунды при 2 rps появляется один токен
+	now = now.Add(500 * time.Millisecond)
+
+	ok, _ = l.allow("a")
+	assert.True(t, ok)
+
+	ok, _ = l.allow("a")
+	assert.False(t, ok)
+
+	// Простаивающие корзины заполняются и выбрасываются
+	now = now.Add(sweepInterval)
+
+	ok, _ = l.allow("c")
+	assert.True(t, ok)
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestMiddleware(t *testing.T) {
+	trusted, err := clientip.ParseTrusted([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	handler := New(slogdiscard.NewDiscardLogger(), Options{
+		Name:           "save",
+		Limit:          Limit{RPS: 0.5, Burst: 1},
+		TrustedProxies: trusted,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(remoteAddr, forwarded string, identity *auth.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/save", nil)
+		req.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		if identity != nil {
+			req = req.WithContext(auth.WithIdentity(req.Context(), *identity))
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, do("203.0.113.1:1000", "", nil).Code)
+
+	rr := do("203.0.113.1:1001", "", nil)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"status":"ERROR","error":"too many requests"}`, rr.Body.String())
+
+	// Клиенты за доверенным прокси считаются по X-Forwarded-For
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1000", "198.51.100.1", nil).Code)
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1000", "198.51.100.2", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.1:1000", "198.51.100.2", nil).Code)
+
+	// Пользователь ограничивается по имени, с какого бы адреса он ни пришел
+	team := &auth.Identity{User: "team-a", Role: auth.RoleUser}
+	assert.Equal(t, http.StatusOK, do("203.0.113.1:1000", "", team).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("203.0.113.9:1000", "", team).Code)
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	handler := New(slogdiscard.NewDiscardLogger(), Options{Name: "redirect"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	for i := 0; i < 100; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/alias", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+}
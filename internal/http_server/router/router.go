@@ -0,0 +1,223 @@
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http_server/handlers/health"
+	"url-shortener/internal/http_server/handlers/keys"
+	"url-shortener/internal/http_server/handlers/redirect"
+	"url-shortener/internal/http_server/handlers/stats"
+	"url-shortener/internal/http_server/handlers/transfer"
+	"url-shortener/internal/http_server/handlers/url/delete"
+	"url-shortener/internal/http_server/handlers/url/list"
+	"url-shortener/internal/http_server/handlers/url/save"
+	"url-shortener/internal/http_server/handlers/url/update"
+	"url-shortener/internal/http_server/middleware/auth"
+	"url-shortener/internal/http_server/middleware/logger"
+	httpmetrics "url-shortener/internal/http_server/middleware/metrics"
+	"url-shortener/internal/http_server/middleware/ratelimit"
+	"url-shortener/internal/http_server/middleware/tracing"
+	"url-shortener/internal/lib/clientip"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// New собирает роутер со всеми маршрутами сервиса. Вынесен из main,
+// чтобы тесты могли поднимать сервер целиком внутри процесса.
+// aliases выдает алиасы ссылкам, сохраненным без своего.
+// tokens проверяет токены JWT, nil отключает вход по ним. m собирает метрики
+// запросов и отдает их на /metrics. checks дополняют проверки хранилища в /readyz.
+func New(
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	repo storage.Repository,
+	aliases save.AliasGenerator,
+	clickRecorder redirect.ClickRecorder,
+	tokens auth.TokenVerifier,
+	m *metrics.Metrics,
+	checks ...health.Check,
+) http.Handler {
+	router := newRouter(log, m)
+
+	mountHealth(router, log, cfg, repo, checks)
+
+	// Метрики, как и пробы, собирает инфраструктура без учетных данных.
+	// На отдельном адресе переходов их нет: он смотрит наружу.
+	router.Handle("/metrics", m.Handler())
+
+	// Маршруты принимают Basic Auth, ключи API и токены JWT. Ключами управляют
+	// только администраторы.
+	authenticated := newAuth(log, cfg, repo, tokens)
+
+	// Все маршруты создания ссылок делят одну корзину
+	saveLimit := newLimit(log, cfg, "save", cfg.RateLimit.Save)
+	deleteLimit := newLimit(log, cfg, "delete", cfg.RateLimit.Delete)
+
+	// С отдельным адресом переходы обслуживает NewRedirect
+	if cfg.Redirect.Address == "" {
+		mountRedirect(router, log, cfg, authenticated, repo, clickRecorder, m)
+	}
+
+	// Импорт принимает CSV и JSONL, поэтому вынесен из группы, где разрешен только JSON
+	router.Group(func(r chi.Router) {
+		r.Use(authenticated)
+		r.Use(middleware.AllowContentType("text/csv", "application/x-ndjson", "application/jsonl", "application/json"))
+
+		r.With(saveLimit).Post("/import", transfer.Import(log, repo, aliases))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(middleware.AllowContentType("application/json"))
+
+		r.Route("/admin/keys", func(r chi.Router) {
+			r.Use(authenticated)
+			r.Use(auth.RequireRole(log, auth.RoleAdmin))
+
+			r.Post("/", keys.Create(log, repo))
+			r.Get("/", keys.List(log, repo))
+			r.Delete("/{id}", keys.Revoke(log, repo))
+		})
+
+		r.Route("/delete", func(r chi.Router) {
+			r.Use(authenticated)
+			r.Use(deleteLimit)
+
+			r.Delete("/{alias:.+}", delete.Delete(log, repo))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(authenticated)
+
+			r.With(saveLimit).Post("/save", save.New(log, repo, aliases, m))
+			r.With(saveLimit).Post("/save/batch", save.NewBatch(log, repo, aliases, cfg.Batch.MaxSize, cfg.Batch.Mode))
+			r.Patch("/url/{alias}", update.New(log, repo))
+			r.Get("/urls", list.New(log, repo))
+			r.Get("/export", transfer.Export(log, repo, cfg.ExportTimeout))
+
+			r.Get("/stats/{alias}", stats.Get(log, repo))
+			r.Get("/stats/{alias}/clicks", stats.TimeSeries(log, repo))
+		})
+	})
+
+	return router
+}
+
+// NewRedirect собирает роутер для отдельного адреса переходов
+// (http_server.redirect.address). API управления на нем нет.
+func NewRedirect(
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	repo storage.Repository,
+	clickRecorder redirect.ClickRecorder,
+	tokens auth.TokenVerifier,
+	m *metrics.Metrics,
+	checks ...health.Check,
+) http.Handler {
+	router := newRouter(log, m)
+
+	mountHealth(router, log, cfg, repo, checks)
+	mountRedirect(router, log, cfg, newAuth(log, cfg, repo, tokens), repo, clickRecorder, m)
+
+	return router
+}
+
+func newRouter(log *slog.Logger, m *metrics.Metrics) *chi.Mux {
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+	router.Use(tracing.New())
+	//router.Use(middleware.Logger)
+	router.Use(logger.New(log))
+	// До Recoverer, чтобы паника попала в метрики как 500
+	router.Use(httpmetrics.New(m))
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+	router.Use(render.SetContentType(render.ContentTypeJSON))
+
+	return router
+}
+
+func newAuth(
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	repo storage.Repository,
+	tokens auth.TokenVerifier,
+) func(http.Handler) http.Handler {
+	return auth.New(log, auth.Options{Realm: "url_shortener", Users: Users(cfg), Keys: repo, Tokens: tokens})
+}
+
+// Users возвращает пользователей Basic Auth из конфига: список
+// http_server.users и, если задан, устаревший http_server.user. Устаревший
+// пользователь — администратор, как и до появления ролей; пользователи из
+// списка без role получают RoleUser.
+func Users(cfg config.HTTPServer) []auth.User {
+	users := make([]auth.User, 0, len(cfg.Users)+1)
+
+	if cfg.User != "" {
+		users = append(users, auth.User{Name: cfg.User, Password: cfg.Password, Role: auth.RoleAdmin})
+	}
+
+	for _, user := range cfg.Users {
+		users = append(users, auth.User{Name: user.Name, PasswordHash: user.PasswordHash, Role: user.Role})
+	}
+
+	return users
+}
+
+// newLimit возвращает ограничение частоты запросов для группы маршрутов name.
+func newLimit(log *slog.Logger, cfg config.HTTPServer, name string, limit config.Limit) func(http.Handler) http.Handler {
+	// Список проверяется при запуске в main. Если он все же испорчен,
+	// заголовкам прокси никто не доверяет — это безопасный исход.
+	trusted, _ := clientip.ParseTrusted(cfg.RateLimit.TrustedProxies)
+
+	return ratelimit.New(log, ratelimit.Options{
+		Name:           name,
+		Limit:          ratelimit.Limit{RPS: limit.RPS, Burst: limit.Burst},
+		TrustedProxies: trusted,
+	})
+}
+
+// mountHealth регистрирует /healthz и /readyz. Их опрашивают балансировщик
+// и systemd, поэтому они открыты без аутентификации и ограничения частоты.
+func mountHealth(
+	router chi.Router,
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	repo storage.Repository,
+	checks []health.Check,
+) {
+	checks = append([]health.Check{
+		{Name: "storage", Check: repo.Ping},
+		{Name: "migrations", Check: repo.CheckSchema},
+	}, checks...)
+
+	router.Get("/healthz", health.Live())
+	router.Get("/readyz", health.Ready(log, cfg.Health.Timeout, checks...))
+}
+
+// mountRedirect регистрирует GET /{alias}. Переходы делают обычные посетители
+// по ссылке из браузера, поэтому без http_server.redirect.auth они не требуют
+// аутентификации, а ограничение частоты считается по адресу клиента.
+func mountRedirect(
+	router chi.Router,
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	authenticated func(http.Handler) http.Handler,
+	repo storage.Repository,
+	clickRecorder redirect.ClickRecorder,
+	m *metrics.Metrics,
+) {
+	router.Group(func(r chi.Router) {
+		if cfg.Redirect.Auth {
+			r.Use(authenticated)
+		}
+		r.Use(newLimit(log, cfg, "redirect", cfg.RateLimit.Redirect))
+
+		r.Get("/{alias}", redirect.Get(log, repo, clickRecorder, m))
+	})
+}
@@ -0,0 +1,527 @@
+package router_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http_server/handlers/health"
+	"url-shortener/internal/http_server/handlers/url/save"
+	"url-shortener/internal/http_server/router"
+	"url-shortener/internal/lib/jwtauth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/password"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage/instrumented"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/traced"
+)
+
+func newAliases(t *testing.T) *random.AliasGenerator {
+	t.Helper()
+
+	aliases, err := random.NewAliasGenerator(random.AliasOptions{})
+	require.NoError(t, err)
+
+	return aliases
+}
+
+func TestRedirectAccess(t *testing.T) {
+	repo := memory.New()
+
+	_, err := repo.SaveURL(context.Background(), "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	base := config.HTTPServer{User: "us", Password: "pass"}
+
+	withRedirect := func(redirect config.Redirect) config.HTTPServer {
+		cfg := base
+		cfg.Redirect = redirect
+		return cfg
+	}
+
+	cases := []struct {
+		name           string
+		handler        http.Handler
+		method         string
+		path           string
+		basicAuth      bool
+		expectedStatus int
+	}{
+		{
+			name:           "Public redirect",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), base, repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodGet,
+			path:           "/promo",
+			expectedStatus: http.StatusFound,
+		},
+		{
+			name:           "Management requires auth",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), base, repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodGet,
+			path:           "/urls",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Redirect with auth enabled",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), withRedirect(config.Redirect{Auth: true}), repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodGet,
+			path:           "/promo",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Redirect with auth enabled and credentials",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), withRedirect(config.Redirect{Auth: true}), repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodGet,
+			path:           "/promo",
+			basicAuth:      true,
+			expectedStatus: http.StatusFound,
+		},
+		{
+			name:           "No redirect on API listener",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), withRedirect(config.Redirect{Address: ":8083"}), repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodGet,
+			path:           "/promo",
+			basicAuth:      true,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Redirect listener",
+			handler:        router.NewRedirect(slogdiscard.NewDiscardLogger(), withRedirect(config.Redirect{Address: ":8083"}), repo, clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodGet,
+			path:           "/promo",
+			expectedStatus: http.StatusFound,
+		},
+		{
+			name:           "No API on redirect listener",
+			handler:        router.NewRedirect(slogdiscard.NewDiscardLogger(), withRedirect(config.Redirect{Address: ":8083"}), repo, clicks.Multi{}, nil, metrics.New()),
+			method:         http.MethodPost,
+			path:           "/save",
+			basicAuth:      true,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(""))
+			if tc.basicAuth {
+				req.SetBasicAuth("us", "pass")
+			}
+
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+
+			if tc.expectedStatus == http.StatusFound {
+				assert.Equal(t, "https://example.com", rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestUsers(t *testing.T) {
+	hash, err := password.Hash("s3cret", password.AlgoBcrypt)
+	require.NoError(t, err)
+
+	cfg := config.HTTPServer{
+		User:     "us",
+		Password: "pass",
+		Users: []config.User{
+			{Name: "team-a", PasswordHash: hash},
+			{Name: "ops", PasswordHash: hash, Role: "admin"},
+		},
+	}
+
+	handler := router.New(slogdiscard.NewDiscardLogger(), cfg, memory.New(), newAliases(t), clicks.Multi{}, nil, metrics.New())
+
+	cases := []struct {
+		name           string
+		user, password string
+		expectedStatus int
+	}{
+		{name: "Legacy user is admin", user: "us", password: "pass", expectedStatus: http.StatusOK},
+		{name: "Listed user without role", user: "team-a", password: "s3cret", expectedStatus: http.StatusForbidden},
+		{name: "Listed admin", user: "ops", password: "s3cret", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+			req.SetBasicAuth(tc.user, tc.password)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestJWTAuth(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC",
+		"kid": "test",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	verifier, err := jwtauth.New(context.Background(), slogdiscard.NewDiscardLogger(), jwtauth.Options{
+		JWKS:     path,
+		Issuer:   "https://idp.example.com",
+		Audience: "url-shortener",
+	})
+	require.NoError(t, err)
+
+	handler := router.New(
+		slogdiscard.NewDiscardLogger(),
+		config.HTTPServer{User: "us", Password: "pass"},
+		memory.New(),
+		newAliases(t),
+		clicks.Multi{},
+		verifier,
+		metrics.New(),
+	)
+
+	token := func(roles ...string) string {
+		t.Helper()
+
+		jwtToken := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+			"sub":   "svc-reports",
+			"iss":   "https://idp.example.com",
+			"aud":   "url-shortener",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": roles,
+		})
+		jwtToken.Header["kid"] = "test"
+
+		signed, err := jwtToken.SignedString(key)
+		require.NoError(t, err)
+
+		return signed
+	}
+
+	cases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "Save with user token",
+			method:         http.MethodPost,
+			path:           "/save",
+			body:           `{"url":"https://example.com","alias":"jwt_link"}`,
+			token:          token(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Admin endpoint with user token",
+			method:         http.MethodGet,
+			path:           "/admin/keys",
+			token:          token(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Admin endpoint with admin token",
+			method:         http.MethodGet,
+			path:           "/admin/keys",
+			token:          token("admin"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Tampered token",
+			method:         http.MethodGet,
+			path:           "/urls",
+			token:          token() + "x",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	repo := memory.New()
+
+	_, err := repo.SaveURL(context.Background(), "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	handler := router.New(slogdiscard.NewDiscardLogger(), config.HTTPServer{
+		User:     "us",
+		Password: "pass",
+		RateLimit: config.RateLimit{
+			Save:     config.Limit{RPS: 0.1, Burst: 1},
+			Redirect: config.Limit{RPS: 0.1, Burst: 2},
+		},
+	}, repo, newAliases(t), clicks.Multi{}, nil, metrics.New())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth("us", "pass")
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/save", `{"url":"https://example.com/a"}`).Code)
+
+	// /save и /save/batch делят одну корзину
+	rr := do(http.MethodPost, "/save/batch", `[{"url":"https://example.com/b"}]`)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "10", rr.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusFound, do(http.MethodGet, "/promo", "").Code)
+	require.Equal(t, http.StatusFound, do(http.MethodGet, "/promo", "").Code)
+	require.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/promo", "").Code)
+
+	// Без лимита в конфиге удаление не ограничено
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/delete/missing", "").Code)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	repo := memory.New()
+
+	cfg := config.HTTPServer{
+		User:      "us",
+		Password:  "pass",
+		Redirect:  config.Redirect{Address: ":8083", Auth: true},
+		RateLimit: config.RateLimit{Redirect: config.Limit{RPS: 0.1, Burst: 1}},
+	}
+
+	saturated := health.Check{Name: "click_events_queue", Check: func(context.Context) error {
+		return clicks.ErrQueueSaturated
+	}}
+
+	cases := []struct {
+		name           string
+		handler        http.Handler
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Liveness without auth",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), cfg, repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK"}`,
+		},
+		{
+			name:           "Readiness",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), cfg, repo, newAliases(t), clicks.Multi{}, nil, metrics.New()),
+			path:           "/readyz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","components":{"storage":{"status":"OK"},"migrations":{"status":"OK"}}}`,
+		},
+		{
+			name:           "Saturated queue",
+			handler:        router.New(slogdiscard.NewDiscardLogger(), cfg, repo, newAliases(t), clicks.Multi{}, nil, metrics.New(), saturated),
+			path:           "/readyz",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"ERROR","error":"not ready","components":{"storage":{"status":"OK"},` +
+				`"migrations":{"status":"OK"},"click_events_queue":{"status":"ERROR","error":"check failed"}}}`,
+		},
+		{
+			name:           "Redirect listener",
+			handler:        router.NewRedirect(slogdiscard.NewDiscardLogger(), cfg, repo, clicks.Multi{}, nil, metrics.New()),
+			path:           "/readyz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"OK","components":{"storage":{"status":"OK"},"migrations":{"status":"OK"}}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Пробы не требуют JSON и не упираются в ограничение частоты переходов
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodGet, tc.path, strings.NewReader("ping"))
+				req.Header.Set("Content-Type", "text/plain")
+
+				rr := httptest.NewRecorder()
+				tc.handler.ServeHTTP(rr, req)
+
+				require.Equal(t, tc.expectedStatus, rr.Code)
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	repo := instrumented.New(memory.New(), m)
+
+	_, err := repo.SaveURL(context.Background(), "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	handler := router.New(slogdiscard.NewDiscardLogger(), config.HTTPServer{User: "us", Password: "pass"}, repo, newAliases(t), clicks.Multi{}, nil, m)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusFound, get("/promo").Code)
+	require.Equal(t, http.StatusNotFound, get("/missing").Code)
+	require.Equal(t, http.StatusNotFound, get("/no/such/route").Code)
+
+	// Метрики отдаются без аутентификации
+	rr := get("/metrics")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+
+	for _, line := range []string{
+		`url_shortener_http_requests_total{method="GET",route="/{alias}",status="302"} 1`,
+		`url_shortener_http_requests_total{method="GET",route="/{alias}",status="404"} 1`,
+		`url_shortener_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`url_shortener_redirects_total{result="hit"} 1`,
+		`url_shortener_redirects_total{result="miss"} 1`,
+		`url_shortener_storage_operation_duration_seconds_count{operation="get_url"} 2`,
+		`url_shortener_storage_operation_duration_seconds_count{operation="save_url"} 1`,
+	} {
+		assert.Contains(t, body, line)
+	}
+
+	// Промах — ожидаемый исход, а не ошибка хранилища
+	assert.NotContains(t, body, "url_shortener_storage_operation_errors_total{")
+	assert.NotContains(t, body, "/missing")
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	repo := traced.New(memory.New(), "memory")
+
+	_, err := repo.SaveURL(context.Background(), "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	handler := router.New(slogdiscard.NewDiscardLogger(), config.HTTPServer{User: "us", Password: "pass"}, repo, newAliases(t), clicks.Multi{}, nil, metrics.New())
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parent  = "00f067aa0ba902b7"
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parent+"-01")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	server, ok := spans["GET /{alias}"]
+	require.True(t, ok, "server span is named after the route pattern")
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, traceID, server.SpanContext().TraceID().String())
+	assert.Equal(t, parent, server.Parent().SpanID().String())
+	assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Equal(t, codes.Unset, server.Status().Code)
+
+	get, ok := spans["storage.GetURL"]
+	require.True(t, ok, "storage call gets a child span")
+	assert.Equal(t, trace.SpanKindClient, get.SpanKind())
+	assert.Equal(t, server.SpanContext().SpanID(), get.Parent().SpanID())
+	assert.Contains(t, get.Attributes(), attribute.String("db.system.name", "memory"))
+	// Промах по алиасу — ожидаемый исход, а не ошибка
+	assert.Equal(t, codes.Unset, get.Status().Code)
+}
+
+func TestReservedAliases(t *testing.T) {
+	repo := memory.New()
+	handler := router.New(slogdiscard.NewDiscardLogger(), config.HTTPServer{User: "us", Password: "pass"}, repo, newAliases(t), clicks.Multi{}, nil, metrics.New())
+
+	// Ссылки кладём в хранилище напрямую, в обход валидации: каждый
+	// зарезервированный алиас должен перехватываться служебным маршрутом.
+	for _, alias := range save.ReservedAliases {
+		_, err := repo.SaveURL(context.Background(), "https://example.com", alias, time.Time{}, "")
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+alias, nil))
+
+		assert.NotEqual(t, http.StatusFound, rr.Code, alias)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(`{"url": "https://example.com", "alias": "readyz"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("us", "pass")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status": "ERROR", "error": "field Alias is reserved for a service route"}`, rr.Body.String())
+}
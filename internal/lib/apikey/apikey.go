@@ -0,0 +1,44 @@
+// Package apikey выпускает ключи API и считает их хэши.
+//
+// Ключ выглядит как "usk_" и 43 символа base64url (32 случайных байта).
+// В хранилище попадает только SHA-256 хэш: ключ достаточно длинный и
+// случайный, поэтому медленный хэш паролей здесь не нужен.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// Scheme — начало каждого ключа, по нему ключ легко найти в логах и конфигах.
+	Scheme = "usk_"
+	// PrefixLength — сколько первых символов ключа хранится открыто.
+	PrefixLength = len(Scheme) + 8
+
+	secretSize = 32
+)
+
+// Generate выпускает новый ключ и возвращает его вместе с открытым префиксом и хэшем.
+func Generate() (key, prefix, hash string, err error) {
+	const op = "lib.apikey.Generate"
+
+	secret := make([]byte, secretSize)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	key = Scheme + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, key[:PrefixLength], Hash(key), nil
+}
+
+// Hash возвращает hex-представление SHA-256 ключа.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, Scheme))
+	assert.Len(t, key, len(Scheme)+43)
+	assert.Equal(t, key[:PrefixLength], prefix)
+	assert.Equal(t, Hash(key), hash)
+	assert.Len(t, hash, 64)
+
+	other, _, otherHash, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, hash, otherHash)
+}
@@ -0,0 +1,107 @@
+// Package clientip определяет адрес клиента за обратными прокси.
+//
+// Заголовкам X-Forwarded-For и X-Real-IP можно верить, только если их
+// выставил доверенный прокси, иначе клиент подставит туда любой адрес.
+// Поэтому цепочка X-Forwarded-For разбирается справа налево, пока адреса
+// принадлежат доверенным сетям, и первым недоверенным адресом считается клиент.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrusted разбирает список доверенных прокси: подсети в нотации CIDR
+// или отдельные адреса.
+func ParseTrusted(list []string) ([]netip.Prefix, error) {
+	const op = "lib.clientip.ParseTrusted"
+
+	prefixes := make([]netip.Prefix, 0, len(list))
+
+	for _, item := range list {
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// FromRequest возвращает адрес клиента. Без доверенных прокси это адрес
+// соединения, заголовки игнорируются.
+func FromRequest(r *http.Request, trusted []netip.Prefix) string {
+	remote, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !isTrusted(remote, trusted) {
+		return remote.String()
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseAddr(strings.TrimSpace(hops[i]))
+			if !ok {
+				// Испорченную цепочку дальше разбирать нельзя: берем последний
+				// адрес, за который ручается доверенный прокси
+				break
+			}
+			if !isTrusted(addr, trusted) {
+				return addr.String()
+			}
+
+			remote = addr
+		}
+
+		return remote.String()
+	}
+
+	if addr, ok := parseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ok {
+		return addr.String()
+	}
+
+	return remote.String()
+}
+
+// parseAddr принимает адрес с портом или без него.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
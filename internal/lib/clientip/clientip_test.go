@@ -0,0 +1,125 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRequest(t *testing.T) {
+	trusted, err := ParseTrusted([]string{"10.0.0.0/8", "192.0.2.10", "::1"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		trusted    bool
+		expected   string
+	}{
+		{
+			name:       "Direct client",
+			remoteAddr: "203.0.113.7:5000",
+			trusted:    true,
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "Headers from untrusted peer are ignored",
+			remoteAddr: "203.0.113.7:5000",
+			forwarded:  []string{"198.51.100.1"},
+			realIP:     "198.51.100.2",
+			trusted:    true,
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "No trusted proxies configured",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"198.51.100.1"},
+			expected:   "10.1.2.3",
+		},
+		{
+			name:       "Single proxy",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"198.51.100.1"},
+			trusted:    true,
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "Spoofed hop before the client",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"1.1.1.1, 198.51.100.1, 192.0.2.10"},
+			trusted:    true,
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "Several headers",
+			remoteAddr: "[::1]:5000",
+			forwarded:  []string{"198.51.100.1", "10.0.0.5"},
+			trusted:    true,
+			expected:   "198.51.100.1",
+		},
+		{
+			name:       "Only trusted hops",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"10.0.0.9"},
+			trusted:    true,
+			expected:   "10.0.0.9",
+		},
+		{
+			name:       "Broken chain",
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"garbage, 10.0.0.9"},
+			trusted:    true,
+			expected:   "10.0.0.9",
+		},
+		{
+			name:       "X-Real-IP",
+			remoteAddr: "10.1.2.3:5000",
+			realIP:     "198.51.100.3",
+			trusted:    true,
+			expected:   "198.51.100.3",
+		},
+		{
+			name:       "IPv4-mapped IPv6",
+			remoteAddr: "[::ffff:203.0.113.7]:5000",
+			trusted:    true,
+			expected:   "203.0.113.7",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			list := trusted
+			if !tc.trusted {
+				list = nil
+			}
+
+			assert.Equal(t, tc.expected, FromRequest(req, list))
+		})
+	}
+}
+
+func TestParseTrusted(t *testing.T) {
+	_, err := ParseTrusted([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+
+	_, err = ParseTrusted([]string{"proxy.local"})
+	require.Error(t, err)
+
+	prefixes, err := ParseTrusted([]string{"10.1.2.3/8", "::ffff:192.0.2.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8", prefixes[0].String())
+	assert.Equal(t, "192.0.2.1/32", prefixes[1].String())
+}
@@ -0,0 +1,339 @@
+// Package jwtauth проверяет токены JWT провайдера удостоверений (OIDC) по
+// набору открытых ключей JWKS из файла или по URL и достает из них имя
+// пользователя и роли.
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	defaultUserClaim       = "sub"
+	defaultRolesClaim      = "roles"
+	defaultAdminRole       = "admin"
+	defaultRefreshInterval = time.Hour
+
+	// minRefreshInterval ограничивает внеочередные загрузки JWKS из-за
+	// токенов с незнакомым kid, чтобы их нельзя было использовать для нагрузки
+	// на провайдера.
+	minRefreshInterval = time.Minute
+
+	maxJWKSSize = 1 << 20
+)
+
+// Алгоритмы подписи с открытым ключом. HMAC не принимается: общий секрет
+// провайдера не публикуется в JWKS.
+var validMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+type Options struct {
+	// JWKS — путь к файлу или http(s)-адрес набора ключей.
+	JWKS string
+	// Issuer и Audience, если заданы, должны совпасть с iss и aud токена.
+	Issuer   string
+	Audience string
+	// UserClaim — claim с именем пользователя, по умолчанию sub.
+	UserClaim string
+	// RolesClaim — claim со списком ролей, вложенные поля через точку
+	// (например, realm_access.roles). Роли — массив строк или строка через пробел.
+	// По умолчанию roles.
+	RolesClaim string
+	// AdminRole — роль, которая дает права администратора, по умолчанию admin.
+	AdminRole string
+	// Leeway — допустимое расхождение часов при проверке exp и nbf.
+	Leeway time.Duration
+	// RefreshInterval — как часто перечитывать JWKS, по умолчанию раз в час.
+	// Токен с незнакомым kid вызывает внеочередное обновление.
+	RefreshInterval time.Duration
+	// Client загружает JWKS по URL, по умолчанию клиент с таймаутом 10 секунд.
+	Client *http.Client
+}
+
+// Subject — пользователь, которому выдан токен.
+type Subject struct {
+	User  string
+	Admin bool
+}
+
+// Verifier проверяет подпись и срок действия токенов. Безопасен для
+// одновременного использования.
+type Verifier struct {
+	log    *slog.Logger
+	opts   Options
+	parser *jwt.Parser
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// refreshing закрывается, когда идущая загрузка JWKS закончится; nil —
+	// загрузки нет.
+	refreshing chan struct{}
+}
+
+// New загружает JWKS и возвращает Verifier. Недоступный при запуске набор
+// ключей — ошибка, чтобы опечатка в конфиге не превращалась в отказ всем токенам.
+func New(ctx context.Context, log *slog.Logger, opts Options) (*Verifier, error) {
+	const op = "lib.jwtauth.New"
+
+	if opts.JWKS == "" {
+		return nil, fmt.Errorf("%s: jwks source is empty", op)
+	}
+	if opts.UserClaim == "" {
+		opts.UserClaim = defaultUserClaim
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = defaultRolesClaim
+	}
+	if opts.AdminRole == "" {
+		opts.AdminRole = defaultAdminRole
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = defaultRefreshInterval
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	v := &Verifier{
+		log: log.With(
+			slog.String("component", "jwtauth"),
+			slog.String("jwks", opts.JWKS),
+		),
+		opts:   opts,
+		parser: jwt.NewParser(parserOpts...),
+	}
+
+	keys, err := v.load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	v.keys, v.fetchedAt = keys, time.Now()
+
+	return v, nil
+}
+
+// VerifyToken проверяет токен и возвращает его владельца. Любой отказ
+// оборачивает ErrInvalidToken.
+func (v *Verifier) VerifyToken(ctx context.Context, token string) (Subject, error) {
+	const op = "lib.jwtauth.VerifyToken"
+
+	claims := jwt.MapClaims{}
+
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		return Subject{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	user, _ := claims[v.opts.UserClaim].(string)
+	if user == "" {
+		return Subject{}, fmt.Errorf("%s: %w: claim %q is missing", op, ErrInvalidToken, v.opts.UserClaim)
+	}
+
+	return Subject{
+		User:  user,
+		Admin: slices.Contains(roles(claims, v.opts.RolesClaim), v.opts.AdminRole),
+	}, nil
+}
+
+// key возвращает ключ по kid, при необходимости перечитывая JWKS. Без kid
+// подходит единственный ключ набора. Плановое обновление идет в фоне, и
+// токены со знакомым kid проверяются по прежним ключам, не дожидаясь
+// провайдера. Ждет загрузки только незнакомый kid.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	keys, fetchedAt, refreshing := v.snapshot()
+
+	if time.Since(fetchedAt) >= v.opts.RefreshInterval {
+		refreshing = v.refresh(ctx, fetchedAt)
+	}
+
+	key, ok := lookup(keys, kid)
+	if !ok && refreshing == nil && time.Since(fetchedAt) >= minRefreshInterval {
+		refreshing = v.refresh(ctx, fetchedAt)
+	}
+	if !ok && refreshing != nil {
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		keys, _, _ = v.snapshot()
+		key, ok = lookup(keys, kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	return key, nil
+}
+
+// snapshot возвращает текущий набор ключей, время последней загрузки и
+// канал идущей загрузки (nil, если ее нет).
+func (v *Verifier) snapshot() (map[string]crypto.PublicKey, time.Time, <-chan struct{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.refreshing == nil {
+		return v.keys, v.fetchedAt, nil
+	}
+
+	return v.keys, v.fetchedAt, v.refreshing
+}
+
+// refresh перечитывает JWKS, если его не перечитали после since, и
+// возвращает канал, который закроется по окончании загрузки. Одновременные
+// вызовы ждут одну и ту же загрузку. Сама загрузка идет без v.mu, под ним
+// только подменяется набор ключей. При ошибке остаются прежние ключи, а
+// следующая попытка будет не раньше чем через minRefreshInterval или RefreshInterval.
+func (v *Verifier) refresh(ctx context.Context, since time.Time) <-chan struct{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.refreshing != nil {
+		return v.refreshing
+	}
+
+	done := make(chan struct{})
+
+	if v.fetchedAt.After(since) {
+		close(done)
+		return done
+	}
+
+	v.refreshing = done
+	v.fetchedAt = time.Now()
+
+	// Загрузку ждут и другие запросы, поэтому отмена запроса, который ее
+	// начал, ее не прерывает. Время ограничено таймаутом клиента.
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		keys, err := v.load(ctx)
+		if err != nil {
+			v.log.Error("failed to refresh jwks", sl.Err(err))
+		}
+
+		v.mu.Lock()
+		if err == nil {
+			v.keys = keys
+		}
+		v.refreshing = nil
+		v.mu.Unlock()
+
+		close(done)
+	}()
+
+	return done
+}
+
+func (v *Verifier) load(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if !strings.HasPrefix(v.opts.JWKS, "http://") && !strings.HasPrefix(v.opts.JWKS, "https://") {
+		data, err := os.ReadFile(v.opts.JWKS)
+		if err != nil {
+			return nil, err
+		}
+
+		return parseJWKS(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.opts.JWKS, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected jwks status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseJWKS(data)
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, ok := keys[kid]
+
+	return key, ok
+}
+
+// roles достает список ролей по пути вида "realm_access.roles".
+func roles(claims jwt.MapClaims, path string) []string {
+	var value any = map[string]any(claims)
+
+	for _, field := range strings.Split(path, ".") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value = obj[field]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		result := make([]string, 0, len(value))
+		for _, role := range value {
+			if s, ok := role.(string); ok {
+				result = append(result, s)
+			}
+		}
+
+		return result
+	default:
+		return nil
+	}
+}
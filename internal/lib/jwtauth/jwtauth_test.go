@@ -0,0 +1,356 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   b64(key.N.Bytes()),
+		"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": key.Curve.Params().Name,
+		"x":   b64(key.X.FillBytes(make([]byte, size))),
+		"y":   b64(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+
+	return data
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(t, keys...), 0o600))
+
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestVerifyToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	path := writeJWKS(t,
+		rsaJWK("rsa-1", rsaKey),
+		ecJWK("ec-1", ecKey),
+		map[string]string{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": b64(edPub)},
+		// Ключ шифрования и неизвестный тип пропускаются
+		map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+	)
+
+	v, err := New(context.Background(), slogdiscard.NewDiscardLogger(), Options{
+		JWKS:       path,
+		Issuer:     "https://idp.example.com",
+		Audience:   "url-shortener",
+		RolesClaim: "realm_access.roles",
+	})
+	require.NoError(t, err)
+
+	valid := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"sub": "svc-reports",
+			"iss": "https://idp.example.com",
+			"aud": "url-shortener",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		for k, val := range extra {
+			claims[k] = val
+		}
+
+		return claims
+	}
+
+	cases := []struct {
+		name     string
+		token    string
+		expected Subject
+		wantErr  bool
+	}{
+		{
+			name:     "RSA",
+			token:    sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, valid(nil)),
+			expected: Subject{User: "svc-reports"},
+		},
+		{
+			name:     "EC admin",
+			token:    sign(t, jwt.SigningMethodES256, "ec-1", ecKey, valid(jwt.MapClaims{"realm_access": map[string]any{"roles": []string{"admin", "viewer"}}})),
+			expected: Subject{User: "svc-reports", Admin: true},
+		},
+		{
+			name:     "Ed25519",
+			token:    sign(t, jwt.SigningMethodEdDSA, "ed-1", edKey, valid(nil)),
+			expected: Subject{User: "svc-reports"},
+		},
+		{
+			name:    "Expired",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, valid(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+			wantErr: true,
+		},
+		{
+			name:    "No expiration",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, valid(jwt.MapClaims{"exp": nil})),
+			wantErr: true,
+		},
+		{
+			name:    "Wrong issuer",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, valid(jwt.MapClaims{"iss": "https://evil.example.com"})),
+			wantErr: true,
+		},
+		{
+			name:    "Wrong audience",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, valid(jwt.MapClaims{"aud": "other"})),
+			wantErr: true,
+		},
+		{
+			name:    "No user",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, valid(jwt.MapClaims{"sub": ""})),
+			wantErr: true,
+		},
+		{
+			name:    "Signed by unknown key",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-1", otherKey, valid(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "Unknown kid",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, valid(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "HMAC with public key as secret",
+			token:   sign(t, jwt.SigningMethodHS256, "rsa-1", rsaKey.N.Bytes(), valid(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "Garbage",
+			token:   "not.a.token",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			subject, err := v.VerifyToken(context.Background(), tc.token)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, subject)
+		})
+	}
+}
+
+func TestVerifyToken_ClaimMapping(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// Без kid подходит единственный ключ набора
+	path := writeJWKS(t, rsaJWK("only", key))
+
+	v, err := New(context.Background(), slogdiscard.NewDiscardLogger(), Options{
+		JWKS:       path,
+		UserClaim:  "email",
+		RolesClaim: "scope",
+		AdminRole:  "shortener:admin",
+	})
+	require.NoError(t, err)
+
+	subject, err := v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "", key, jwt.MapClaims{
+		"sub":   "12345",
+		"email": "ops@example.com",
+		"scope": "openid shortener:admin",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, Subject{User: "ops@example.com", Admin: true}, subject)
+}
+
+func TestVerifyToken_RemoteRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		current atomic.Value
+		fetches atomic.Int32
+	)
+
+	current.Store(jwksJSON(t, rsaJWK("old", oldKey)))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(current.Load().([]byte))
+	}))
+	defer srv.Close()
+
+	v, err := New(context.Background(), slogdiscard.NewDiscardLogger(), Options{JWKS: srv.URL})
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{"sub": "svc", "exp": time.Now().Add(time.Hour).Unix()}
+
+	_, err = v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "old", oldKey, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// Провайдер сменил ключ: незнакомый kid перечитывает набор
+	current.Store(jwksJSON(t, rsaJWK("new", newKey)))
+	v.fetchedAt = v.fetchedAt.Add(-minRefreshInterval)
+
+	_, err = v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "new", newKey, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// Повторный незнакомый kid сразу после обновления не дергает провайдера
+	_, err = v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "unknown", newKey, claims))
+	require.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+// Пока провайдер медленно отдает JWKS, токены со знакомым kid проверяются
+// по прежним ключам, а незнакомые kid ждут одну общую загрузку.
+func TestVerifyToken_RefreshDoesNotBlock(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		fetches atomic.Int32
+		release = make(chan struct{})
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		_, _ = w.Write(jwksJSON(t, rsaJWK("current", key)))
+	}))
+	defer srv.Close()
+
+	v, err := New(context.Background(), slogdiscard.NewDiscardLogger(), Options{JWKS: srv.URL})
+	require.NoError(t, err)
+
+	v.mu.Lock()
+	v.fetchedAt = v.fetchedAt.Add(-defaultRefreshInterval)
+	v.mu.Unlock()
+
+	claims := jwt.MapClaims{"sub": "svc", "exp": time.Now().Add(time.Hour).Unix()}
+
+	// Плановое обновление зависло у провайдера, но проверка не ждет его
+	for range 3 {
+		_, err = v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "current", key, claims))
+		require.NoError(t, err)
+	}
+
+	unknown := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := v.VerifyToken(context.Background(), sign(t, jwt.SigningMethodRS256, "rotated", key, claims))
+			unknown <- err
+		}()
+	}
+
+	select {
+	case err := <-unknown:
+		t.Fatalf("unknown kid returned before jwks was loaded: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	for range 2 {
+		require.ErrorIs(t, <-unknown, ErrInvalidToken)
+	}
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestNew_InvalidSource(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	_, err := New(context.Background(), log, Options{})
+	require.Error(t, err)
+
+	_, err = New(context.Background(), log, Options{JWKS: filepath.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "empty.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys":[]}`), 0o600))
+
+	_, err = New(context.Background(), log, Options{JWKS: path})
+	require.Error(t, err)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err = New(context.Background(), log, Options{JWKS: srv.URL})
+	require.Error(t, err)
+}
@@ -1,7 +1,10 @@
 package sl
 
 import (
+	"context"
 	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Err(err error) slog.Attr {
@@ -10,3 +13,15 @@ func Err(err error) slog.Attr {
 		Value: slog.StringValue(err.Error()),
 	}
 }
+
+// TraceID возвращает идентификатор трассы из ctx, чтобы запись лога можно
+// было найти по трассе. Без активного span возвращается пустой атрибут,
+// который slog пропускает.
+func TraceID(ctx context.Context) slog.Attr {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return slog.Attr{}
+	}
+
+	return slog.String("trace_id", spanContext.TraceID().String())
+}
@@ -0,0 +1,155 @@
+// Package password считает и проверяет хэши паролей пользователей из конфига.
+//
+// Поддерживаются bcrypt ("$2a$...", "$2b$...") и argon2id в формате PHC:
+// "$argon2id$v=19$m=65536,t=3,p=4$<соль>$<хэш>". Алгоритм определяется
+// по префиксу хэша, поэтому в конфиге можно смешивать оба вида.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Алгоритмы хэширования.
+const (
+	AlgoBcrypt   = "bcrypt"
+	AlgoArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Параметры argon2id для новых хэшей — второй рекомендуемый набор из RFC 9106.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonSaltLen = 16
+	argonKeyLen  = 32
+)
+
+// Hash возвращает хэш пароля выбранным алгоритмом.
+func Hash(password, algo string) (string, error) {
+	const op = "lib.password.Hash"
+
+	switch algo {
+	case AlgoBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		return string(hash), nil
+	case AlgoArgon2id:
+		salt := make([]byte, argonSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, argonMemory, argonTime, argonThreads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	default:
+		return "", fmt.Errorf("%s: unknown algorithm %q", op, algo)
+	}
+}
+
+// Verify сравнивает пароль с хэшем. Несовпадение — не ошибка: ошибка означает,
+// что хэш нельзя разобрать.
+func Verify(hash, password string) (bool, error) {
+	const op = "lib.password.Verify"
+
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return true, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := parseArgon2id(hash)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		actual := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+
+		return subtle.ConstantTimeCompare(actual, key) == 1, nil
+	default:
+		return false, fmt.Errorf("%s: %w", op, ErrUnknownHash)
+	}
+}
+
+// Validate проверяет, что хэш можно использовать в Verify.
+func Validate(hash string) error {
+	const op = "lib.password.Validate"
+
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	case strings.HasPrefix(hash, "$argon2id$"):
+		if _, _, _, err := parseArgon2id(hash); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return fmt.Errorf("%s: %w", op, ErrUnknownHash)
+	}
+
+	return nil
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func parseArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", соль, хэш
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	if params.memory == 0 || params.time == 0 || params.threads == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2 hash")
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashVerify(t *testing.T) {
+	for _, algo := range []string{AlgoBcrypt, AlgoArgon2id} {
+		t.Run(algo, func(t *testing.T) {
+			hash, err := Hash("s3cret", algo)
+			require.NoError(t, err)
+			require.NoError(t, Validate(hash))
+
+			ok, err := Verify(hash, "s3cret")
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = Verify(hash, "wrong")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			other, err := Hash("s3cret", algo)
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, other, "salt must differ")
+		})
+	}
+}
+
+func TestVerifyHashParams(t *testing.T) {
+	// Параметры argon2id берутся из самого хэша, а не из настроек по умолчанию
+	salt := []byte("somesalt")
+	key := argon2.IDKey([]byte("s3cret"), salt, 2, 16, 1, 16)
+	argonHash := fmt.Sprintf("$argon2id$v=19$m=16,t=2,p=1$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{name: "argon2id", hash: argonHash},
+		{name: "bcrypt", hash: string(bcryptHash)},
+		{name: "Plain text", hash: "s3cret", wantErr: true},
+		{name: "Zero memory", hash: "$argon2id$v=19$m=0,t=2,p=1$c29tZXNhbHQ$aGFzaA", wantErr: true},
+		{name: "Unsupported version", hash: "$argon2id$v=16$m=16,t=2,p=1$c29tZXNhbHQ$aGFzaA", wantErr: true},
+		{name: "Broken bcrypt", hash: "$2a$10$short", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := Verify(tc.hash, "s3cret")
+			if tc.wantErr {
+				require.Error(t, err)
+				assert.Error(t, Validate(tc.hash))
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, Validate(tc.hash))
+			assert.True(t, ok)
+		})
+	}
+}
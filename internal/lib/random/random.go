@@ -1,17 +1,132 @@
 package random
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
 )
 
-var globalRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+const (
+	// DefaultAlphabet — латинские буквы и цифры.
+	DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// UnambiguousAlphabet — DefaultAlphabet без похожих символов 0/O/o, 1/l/I,
+	// чтобы алиас можно было без ошибок переписать с экрана или бумаги.
+	UnambiguousAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
 
-func NewRandomString(size int) string {
-	chars := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-	b := make([]rune, size)
-	for i := range b {
-		b[i] = chars[globalRand.Intn(len(chars))]
+	DefaultAliasLength = 6
+)
+
+// Символы, допустимые в алиасе: он становится сегментом пути, поэтому
+// остальные пришлось бы экранировать.
+const aliasChars = DefaultAlphabet + "-_"
+
+var (
+	ErrInvalidAlphabet = errors.New("alphabet must have 2 to 256 unique characters from a-z, A-Z, 0-9, '-' and '_'")
+	ErrInvalidPrefix   = errors.New("prefix may only contain a-z, A-Z, 0-9, '-' and '_'")
+	ErrInvalidLength   = errors.New("alias length must be positive")
+)
+
+// AliasOptions — вид генерируемых алиасов: Prefix и Length случайных символов
+// из Alphabet. Пустой Alphabet и нулевой Length заменяются значениями по умолчанию.
+type AliasOptions struct {
+	Alphabet string
+	Length   int
+	Prefix   string
+}
+
+// AliasGenerator выдает алиасы из crypto/rand. Безопасен для конкурентного использования.
+type AliasGenerator struct {
+	alphabet string
+	length   int
+	prefix   string
+	// limit — граница, ниже которой случайный байт берется по модулю
+	// len(alphabet) без смещения в пользу первых символов.
+	limit int
+}
+
+func NewAliasGenerator(opts AliasOptions) (*AliasGenerator, error) {
+	const op = "lib.random.NewAliasGenerator"
+
+	if opts.Alphabet == "" {
+		opts.Alphabet = DefaultAlphabet
+	}
+	if opts.Length == 0 {
+		opts.Length = DefaultAliasLength
+	}
+
+	if opts.Length < 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidLength)
+	}
+	if !validAlphabet(opts.Alphabet) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidAlphabet)
+	}
+	if strings.Trim(opts.Prefix, aliasChars) != "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidPrefix)
+	}
+
+	return &AliasGenerator{
+		alphabet: opts.Alphabet,
+		length:   opts.Length,
+		prefix:   opts.Prefix,
+		limit:    256 - 256%len(opts.Alphabet),
+	}, nil
+}
+
+func (g *AliasGenerator) Generate() string {
+	return g.prefix + g.randomString(g.length)
+}
+
+func (g *AliasGenerator) randomString(size int) string {
+	b := make([]byte, size)
+	// С запасом на отброшенные байты, чтобы обычно хватало одного чтения
+	buf := make([]byte, size+size/2+8)
+
+	for i := 0; i < size; {
+		// crypto/rand.Read не возвращает ошибок
+		_, _ = rand.Read(buf)
+
+		for _, c := range buf {
+			if int(c) >= g.limit {
+				continue
+			}
+
+			b[i] = g.alphabet[int(c)%len(g.alphabet)]
+			i++
+
+			if i == size {
+				break
+			}
+		}
 	}
+
 	return string(b)
 }
+
+var defaultGenerator = &AliasGenerator{
+	alphabet: DefaultAlphabet,
+	limit:    256 - 256%len(DefaultAlphabet),
+}
+
+// NewRandomString возвращает случайную строку из DefaultAlphabet.
+func NewRandomString(size int) string {
+	return defaultGenerator.randomString(size)
+}
+
+func validAlphabet(alphabet string) bool {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return false
+	}
+
+	seen := make(map[rune]bool, len(alphabet))
+
+	for _, c := range alphabet {
+		if seen[c] || !strings.ContainsRune(aliasChars, c) {
+			return false
+		}
+
+		seen[c] = true
+	}
+
+	return true
+}
@@ -1,8 +1,11 @@
 package random
 
 import (
-	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewRandomString(t *testing.T) {
@@ -60,3 +63,70 @@ func TestNewRandomString(t *testing.T) {
 		})
 	}
 }
+
+func TestAliasGenerator(t *testing.T) {
+	g, err := NewAliasGenerator(AliasOptions{Alphabet: UnambiguousAlphabet, Length: 8, Prefix: "go-"})
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+
+	for range 1000 {
+		alias := g.Generate()
+
+		require.Len(t, alias, len("go-")+8)
+		require.True(t, strings.HasPrefix(alias, "go-"))
+		require.Empty(t, strings.Trim(alias[len("go-"):], UnambiguousAlphabet), alias)
+		require.False(t, seen[alias], "duplicate alias %s", alias)
+
+		seen[alias] = true
+	}
+}
+
+func TestAliasGenerator_Defaults(t *testing.T) {
+	g, err := NewAliasGenerator(AliasOptions{})
+	require.NoError(t, err)
+
+	alias := g.Generate()
+	require.Len(t, alias, DefaultAliasLength)
+	require.Empty(t, strings.Trim(alias, DefaultAlphabet))
+}
+
+// Каждый символ алфавита должен выпадать примерно одинаково часто.
+func TestAliasGenerator_Uniform(t *testing.T) {
+	const alphabet = "abc"
+
+	g, err := NewAliasGenerator(AliasOptions{Alphabet: alphabet, Length: 30000})
+	require.NoError(t, err)
+
+	counts := make(map[rune]int)
+	for _, c := range g.Generate() {
+		counts[c]++
+	}
+
+	for _, c := range alphabet {
+		assert.InDelta(t, 10000, counts[c], 500, "char %c", c)
+	}
+}
+
+func TestNewAliasGenerator_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		opts AliasOptions
+		err  error
+	}{
+		{name: "Single char alphabet", opts: AliasOptions{Alphabet: "a"}, err: ErrInvalidAlphabet},
+		{name: "Duplicate chars", opts: AliasOptions{Alphabet: "abca"}, err: ErrInvalidAlphabet},
+		{name: "Slash in alphabet", opts: AliasOptions{Alphabet: "ab/"}, err: ErrInvalidAlphabet},
+		{name: "Dot in alphabet", opts: AliasOptions{Alphabet: "ab."}, err: ErrInvalidAlphabet},
+		{name: "Non-ASCII alphabet", opts: AliasOptions{Alphabet: "абв"}, err: ErrInvalidAlphabet},
+		{name: "Negative length", opts: AliasOptions{Length: -1}, err: ErrInvalidLength},
+		{name: "Prefix with slash", opts: AliasOptions{Prefix: "a/b"}, err: ErrInvalidPrefix},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAliasGenerator(tc.opts)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
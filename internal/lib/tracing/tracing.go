@@ -0,0 +1,109 @@
+// Package tracing настраивает OpenTelemetry: глобальный TracerProvider,
+// распространение контекста по W3C traceparent и экспорт spans в stdout
+// или в OTLP-коллектор.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+// Куда отправлять spans.
+const (
+	// ExporterNone не экспортирует spans, но trace_id все равно выдается и
+	// попадает в логи и в исходящий traceparent.
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	// ExporterOTLP отправляет spans по OTLP/HTTP, например в локальный коллектор.
+	ExporterOTLP = "otlp"
+)
+
+const defaultServiceName = "url-shortener"
+
+// ErrNoEndpoint — выбран ExporterOTLP, но адрес коллектора не задан ни в
+// Options, ни в переменных окружения. Экспортер ушел бы на localhost и
+// сыпал ошибками в лог.
+var ErrNoEndpoint = errors.New("otlp exporter needs an endpoint or OTEL_EXPORTER_OTLP_ENDPOINT")
+
+type Options struct {
+	Exporter string
+	// Endpoint — адрес коллектора OTLP/HTTP, например http://localhost:4318.
+	// Пустой адрес берется из OTEL_EXPORTER_OTLP_ENDPOINT или
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, без них Setup вернет ErrNoEndpoint.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio — доля трассируемых запросов без входящего traceparent.
+	// Для входящего решение о сэмплировании принимает вызывающий.
+	SampleRatio float64
+	// Output — куда пишет ExporterStdout, по умолчанию os.Stdout.
+	Output io.Writer
+}
+
+// Setup устанавливает глобальные TracerProvider и propagator. Возвращенная
+// функция дописывает накопленные spans и останавливает экспорт.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	const op = "lib.tracing.Setup"
+
+	if opts.ServiceName == "" {
+		opts.ServiceName = defaultServiceName
+	}
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(opts.ServiceName))),
+	}
+
+	switch opts.Exporter {
+	case ExporterNone, "":
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(opts.Output))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		switch {
+		case opts.Endpoint != "":
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "":
+			return nil, fmt.Errorf("%s: %w", op, ErrNoEndpoint)
+		}
+
+		// Соединение устанавливается лениво, поэтому недоступный при запуске
+		// коллектор не мешает старту: spans будут отброшены с ошибкой в логе otel.
+		exporter, err := otlptracehttp.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, opts.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
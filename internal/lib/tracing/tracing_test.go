@@ -0,0 +1,71 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"url-shortener/internal/lib/tracing"
+)
+
+func TestSetup(t *testing.T) {
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	t.Run("Stdout exporter", func(t *testing.T) {
+		var out bytes.Buffer
+
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+			Exporter:    tracing.ExporterStdout,
+			ServiceName: "test-service",
+			SampleRatio: 1,
+			Output:      &out,
+		})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+		assert.True(t, span.SpanContext().IsSampled())
+		span.End()
+
+		// Spans выгружаются пачками, shutdown дописывает остаток
+		require.NoError(t, shutdown(context.Background()))
+		assert.Contains(t, out.String(), `"Name":"test-span"`)
+		assert.Contains(t, out.String(), "test-service")
+	})
+
+	t.Run("OTLP without endpoint", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterOTLP, SampleRatio: 1})
+		require.ErrorIs(t, err, tracing.ErrNoEndpoint)
+	})
+
+	t.Run("OTLP endpoint from environment", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterOTLP, SampleRatio: 1})
+		require.NoError(t, err)
+		require.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("No exporter still issues trace ids", func(t *testing.T) {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone, SampleRatio: 1})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+		span.End()
+
+		assert.True(t, span.SpanContext().HasTraceID())
+		require.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("Unknown exporter", func(t *testing.T) {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "jaeger"})
+		require.Error(t, err)
+	})
+}
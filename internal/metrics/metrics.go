@@ -0,0 +1,190 @@
+// Package metrics собирает метрики Prometheus: запросы к HTTP API, операции
+// хранилища, коллизии случайных алиасов, результаты переходов и очередь
+// событий переходов.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/cached"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "url_shortener"
+
+// Metrics хранит коллекторы в собственном реестре, а не в глобальном, чтобы
+// тесты и несколько роутеров в одном процессе не конфликтовали при регистрации.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+	aliasCollisions prometheus.Counter
+	redirects       *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, chi route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, chi route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage operation latency.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_errors_total",
+			Help:      "Failed storage operations by kind: timeout, canceled or other.",
+		}, []string{"operation", "kind"}),
+		aliasCollisions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alias_collisions_total",
+			Help:      "Random aliases that were already taken and had to be regenerated.",
+		}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Redirect lookups by result: hit, miss or expired.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.storageDuration,
+		m.storageErrors,
+		m.aliasCollisions,
+		m.redirects,
+	)
+
+	return m
+}
+
+// Handler отдает метрики в формате Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest учитывает обработанный HTTP-запрос. route — шаблон
+// маршрута chi, а не путь, чтобы алиасы не плодили серии.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.requestDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveStorage учитывает операцию хранилища. Ожидаемые исходы вроде
+// ErrUrlNotFound и ErrUrlExist ошибками не считаются, см. storage.IsFailure.
+func (m *Metrics) ObserveStorage(operation string, duration time.Duration, err error) {
+	m.storageDuration.WithLabelValues(operation).Observe(duration.Seconds())
+
+	if kind := errorKind(err); kind != "" {
+		m.storageErrors.WithLabelValues(operation, kind).Inc()
+	}
+}
+
+func (m *Metrics) RecordAliasCollision() {
+	m.aliasCollisions.Inc()
+}
+
+func (m *Metrics) RecordRedirect(result string) {
+	m.redirects.WithLabelValues(result).Inc()
+}
+
+// RegisterURLCache отдает счетчики кэша ссылок. stats вызывается при
+// каждом чтении /metrics.
+func (m *Metrics) RegisterURLCache(stats func() cached.Stats) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "url_cache_requests_total",
+			Help:        "URL cache lookups by result: hit or miss. Cached not found and expired aliases count as hits.",
+			ConstLabels: prometheus.Labels{"result": "hit"},
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "url_cache_requests_total",
+			Help:        "URL cache lookups by result: hit or miss. Cached not found and expired aliases count as hits.",
+			ConstLabels: prometheus.Labels{"result": "miss"},
+		}, func() float64 { return float64(stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "url_cache_evictions_total",
+			Help:      "Aliases evicted from the URL cache because it was full.",
+		}, func() float64 { return float64(stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "url_cache_entries",
+			Help:      "Aliases currently held in the URL cache.",
+		}, func() float64 { return float64(stats().Entries) }),
+	)
+}
+
+// RegisterClickQueue отдает счетчики очереди событий переходов, чтобы
+// отброшенные при переполнении события было видно без чтения логов. stats
+// вызывается при каждом чтении /metrics.
+func (m *Metrics) RegisterClickQueue(stats func() clicks.QueueStats) {
+	eventsOpts := func(result string) prometheus.CounterOpts {
+		return prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "click_events_total",
+			Help:        "Click events by result: enqueued, dropped because the queue was full, written or failed to write.",
+			ConstLabels: prometheus.Labels{"result": result},
+		}
+	}
+
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(eventsOpts("enqueued"), func() float64 { return float64(stats().Enqueued) }),
+		prometheus.NewCounterFunc(eventsOpts("dropped"), func() float64 { return float64(stats().Dropped) }),
+		prometheus.NewCounterFunc(eventsOpts("written"), func() float64 { return float64(stats().Written) }),
+		prometheus.NewCounterFunc(eventsOpts("failed"), func() float64 { return float64(stats().Failed) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "click_events_queue_length",
+			Help:      "Click events waiting in the queue to be written.",
+		}, func() float64 { return float64(stats().Length) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "click_events_queue_capacity",
+			Help:      "Capacity of the click events queue.",
+		}, func() float64 { return float64(stats().Capacity) }),
+	)
+}
+
+func errorKind(err error) string {
+	switch {
+	case !storage.IsFailure(err):
+		return ""
+	case errors.Is(err, storage.ErrQueryTimeout):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "other"
+	}
+}
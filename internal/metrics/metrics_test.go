@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/cached"
+)
+
+func TestObserveStorage(t *testing.T) {
+	m := New()
+
+	m.ObserveStorage("get_url", time.Millisecond, nil)
+	m.ObserveStorage("get_url", time.Millisecond, fmt.Errorf("op: %w", storage.ErrUrlNotFound))
+	m.ObserveStorage("get_url", time.Millisecond, fmt.Errorf("op: %w", storage.ErrQueryTimeout))
+	m.ObserveStorage("save_url", time.Millisecond, storage.ErrUrlExist)
+	m.ObserveStorage("save_url", time.Millisecond, context.Canceled)
+	m.ObserveStorage("delete_url", time.Millisecond, errors.New("disk I/O error"))
+
+	assert.Equal(t, 3, testutil.CollectAndCount(m.storageDuration))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.storageErrors))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("get_url", "timeout")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("save_url", "canceled")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("delete_url", "other")))
+}
+
+func TestRegisterURLCache(t *testing.T) {
+	m := New()
+	m.RegisterURLCache(func() cached.Stats {
+		return cached.Stats{Hits: 7, Misses: 3, Evictions: 1, Entries: 2}
+	})
+
+	expected := `
+# HELP url_shortener_url_cache_requests_total URL cache lookups by result: hit or miss. Cached not found and expired aliases count as hits.
+# TYPE url_shortener_url_cache_requests_total counter
+url_shortener_url_cache_requests_total{result="hit"} 7
+url_shortener_url_cache_requests_total{result="miss"} 3
+# HELP url_shortener_url_cache_entries Aliases currently held in the URL cache.
+# TYPE url_shortener_url_cache_entries gauge
+url_shortener_url_cache_entries 2
+`
+
+	assert.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"url_shortener_url_cache_requests_total", "url_shortener_url_cache_entries"))
+}
+
+func TestRegisterClickQueue(t *testing.T) {
+	m := New()
+	m.RegisterClickQueue(func() clicks.QueueStats {
+		return clicks.QueueStats{Enqueued: 120, Dropped: 5, Written: 100, Failed: 10, Length: 10, Capacity: 64}
+	})
+
+	expected := `
+# HELP url_shortener_click_events_total Click events by result: enqueued, dropped because the queue was full, written or failed to write.
+# TYPE url_shortener_click_events_total counter
+url_shortener_click_events_total{result="dropped"} 5
+url_shortener_click_events_total{result="enqueued"} 120
+url_shortener_click_events_total{result="failed"} 10
+url_shortener_click_events_total{result="written"} 100
+# HELP url_shortener_click_events_queue_length Click events waiting in the queue to be written.
+# TYPE url_shortener_click_events_queue_length gauge
+url_shortener_click_events_queue_length 10
+`
+
+	assert.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"url_shortener_click_events_total", "url_shortener_click_events_queue_length"))
+}
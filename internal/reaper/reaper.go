@@ -0,0 +1,121 @@
+// Package reaper периодически удаляет или архивирует ссылки с истекшим сроком действия.
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+const (
+	ModeDelete  = "delete"
+	ModeArchive = "archive"
+)
+
+type ExpiredPurger interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	ArchiveExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Reaper struct {
+	log      *slog.Logger
+	purger   ExpiredPurger
+	interval time.Duration
+	mode     string
+
+	// ctx отменяется в Stop и прерывает текущий проход очистки.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(log *slog.Logger, purger ExpiredPurger, interval time.Duration, mode string) (*Reaper, error) {
+	const op = "reaper.New"
+
+	if mode != ModeDelete && mode != ModeArchive {
+		return nil, fmt.Errorf("%s: unknown mode %q", op, mode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Reaper{
+		log: log.With(
+			slog.String("component", "reaper"),
+			slog.String("mode", mode),
+		),
+		purger:   purger,
+		interval: interval,
+		mode:     mode,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start запускает фоновую очистку. Нулевой интервал отключает ее.
+func (r *Reaper) Start() {
+	if r.interval <= 0 {
+		r.log.Info("reaper disabled")
+		close(r.done)
+		return
+	}
+
+	r.log.Info("reaper started", slog.String("interval", r.interval.String()))
+
+	go r.loop()
+}
+
+// Stop останавливает очистку: текущий проход прерывается отменой контекста,
+// Stop ждет, пока он вернется.
+func (r *Reaper) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *Reaper) loop() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			r.log.Info("reaper stopped")
+			return
+		case <-ticker.C:
+			_, _ = r.RunOnce(r.ctx)
+		}
+	}
+}
+
+// RunOnce выполняет один проход очистки и возвращает число обработанных ссылок.
+func (r *Reaper) RunOnce(ctx context.Context) (int64, error) {
+	const op = "reaper.RunOnce"
+
+	now := time.Now()
+
+	var (
+		purged int64
+		err    error
+	)
+
+	if r.mode == ModeArchive {
+		purged, err = r.purger.ArchiveExpired(ctx, now)
+	} else {
+		purged, err = r.purger.DeleteExpired(ctx, now)
+	}
+
+	if err != nil {
+		r.log.Error("failed to purge expired urls", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if purged > 0 {
+		r.log.Info("expired urls purged", slog.Int64("count", purged))
+	}
+
+	return purged, nil
+}
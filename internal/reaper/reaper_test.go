@@ -0,0 +1,119 @@
+package reaper_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/reaper"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+func TestReaper_RunOnce(t *testing.T) {
+	for _, mode := range []string{reaper.ModeDelete, reaper.ModeArchive} {
+		t.Run(mode, func(t *testing.T) {
+			ctx := context.Background()
+			repo := memory.New()
+
+			_, err := repo.SaveURL(ctx, "https://example.com/old", "old", time.Now().Add(-time.Minute), "")
+			require.NoError(t, err)
+			_, err = repo.SaveURL(ctx, "https://example.com/new", "new", time.Now().Add(time.Hour), "")
+			require.NoError(t, err)
+
+			r, err := reaper.New(slogdiscard.NewDiscardLogger(), repo, time.Minute, mode)
+			require.NoError(t, err)
+
+			purged, err := r.RunOnce(ctx)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), purged)
+
+			_, err = repo.GetURL(ctx, "old")
+			require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+			_, err = repo.GetURL(ctx, "new")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestReaper_StartStop(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+
+	_, err := repo.SaveURL(ctx, "https://example.com/old", "old", time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	r, err := reaper.New(slogdiscard.NewDiscardLogger(), repo, 10*time.Millisecond, reaper.ModeDelete)
+	require.NoError(t, err)
+
+	r.Start()
+
+	require.Eventually(t, func() bool {
+		_, err := repo.GetURL(ctx, "old")
+		return err == storage.ErrUrlNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	r.Stop()
+	r.Stop()
+}
+
+// hangingPurger блокируется до отмены контекста, как зависший запрос к базе.
+type hangingPurger struct {
+	started chan struct{}
+	once    sync.Once
+}
+
+func (p *hangingPurger) DeleteExpired(ctx context.Context, _ time.Time) (int64, error) {
+	p.once.Do(func() { close(p.started) })
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (p *hangingPurger) ArchiveExpired(ctx context.Context, before time.Time) (int64, error) {
+	return p.DeleteExpired(ctx, before)
+}
+
+func TestReaper_StopCancelsRunningPass(t *testing.T) {
+	purger := &hangingPurger{started: make(chan struct{})}
+
+	r, err := reaper.New(slogdiscard.NewDiscardLogger(), purger, 10*time.Millisecond, reaper.ModeDelete)
+	require.NoError(t, err)
+
+	r.Start()
+
+	select {
+	case <-purger.started:
+	case <-time.After(time.Second):
+		t.Fatal("reaper pass did not start")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		r.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not interrupt the running pass")
+	}
+}
+
+func TestReaper_Disabled(t *testing.T) {
+	r, err := reaper.New(slogdiscard.NewDiscardLogger(), memory.New(), 0, reaper.ModeDelete)
+	require.NoError(t, err)
+
+	r.Start()
+	r.Stop()
+}
+
+func TestNew_UnknownMode(t *testing.T) {
+	_, err := reaper.New(slogdiscard.NewDiscardLogger(), memory.New(), time.Minute, "drop")
+	require.Error(t, err)
+}
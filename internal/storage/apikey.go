@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey — ключ доступа к API. Сам ключ не хранится, только его хэш и
+// первые символы, по которым ключ можно узнать в списке.
+// Нулевой RevokedAt означает действующий ключ.
+type APIKey struct {
+	ID        int64
+	User      string
+	Role      string
+	Prefix    string
+	CreatedAt time.Time
+	RevokedAt time.Time
+}
@@ -0,0 +1,269 @@
+// Package cached кэширует GetURL перед хранилищем: переход по популярной
+// ссылке не должен каждый раз ходить в базу.
+package cached
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+type Options struct {
+	// Size — сколько алиасов держать в кэше. Сверх него вытесняются те,
+	// к которым дольше всего не обращались.
+	Size int
+	// TTL — сколько живет найденная ссылка.
+	TTL time.Duration
+	// NegativeTTL — сколько помнить, что алиаса нет или он истек, чтобы
+	// перебор несуществующих алиасов не доходил до базы.
+	NegativeTTL time.Duration
+}
+
+// Stats — счетчики с момента создания кэша.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+// Storage оборачивает хранилище. Записи этого процесса, меняющие ссылку,
+// сразу сбрасывают ее из кэша. Изменения, сделанные в обход него (другим
+// инстансом или командой import), становятся видны не позже чем через TTL.
+// Ссылка со сроком действия хранится не дольше этого срока.
+type Storage struct {
+	storage.Repository
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // в начале — последние прочитанные
+	// generation растет при каждом сбросе. Чтение из базы, начатое до сброса,
+	// не кладет в кэш результат, который мог устареть.
+	generation uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type entry struct {
+	key string
+	url string
+	// urlExpiresAt — срок действия самой ссылки, expiresAt — записи в кэше.
+	urlExpiresAt time.Time
+	err          error
+	expiresAt    time.Time
+}
+
+func New(repo storage.Repository, opts Options) *Storage {
+	return &Storage{
+		Repository: repo,
+		opts:       opts,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element, opts.Size),
+		lru:        list.New(),
+	}
+}
+
+// GetURL отдает ссылку из кэша, а при промахе читает ее из хранилища. Кроме
+// найденных ссылок кэшируются ErrUrlNotFound и ErrUrlExpired, сбои — нет.
+func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+	url, _, err := s.GetURLExpiry(ctx, alias)
+
+	return url, err
+}
+
+func (s *Storage) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	key := cacheKey(alias)
+
+	if e, ok := s.lookup(key); ok {
+		s.hits.Add(1)
+		return e.url, e.urlExpiresAt, e.err
+	}
+
+	s.misses.Add(1)
+
+	generation := s.currentGeneration()
+
+	url, expiresAt, err := s.Repository.GetURLExpiry(ctx, alias)
+
+	switch {
+	case err == nil:
+		ttl := s.opts.TTL
+		// Истекшая ссылка должна отвечать 410, а не редиректом из кэша
+		if left := expiresAt.Sub(s.now()); !expiresAt.IsZero() && left < ttl {
+			ttl = left
+		}
+
+		s.store(generation, entry{key: key, url: url, urlExpiresAt: expiresAt}, ttl)
+	case errors.Is(err, storage.ErrUrlNotFound), errors.Is(err, storage.ErrUrlExpired):
+		s.store(generation, entry{key: key, err: err}, s.opts.NegativeTTL)
+	}
+
+	return url, expiresAt, err
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	id, err := s.Repository.SaveURL(ctx, urlToSave, alias, expiresAt, owner)
+	s.invalidate(alias)
+
+	return id, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.NewURL, atomic bool) ([]storage.SaveResult, error) {
+	results, err := s.Repository.SaveURLs(ctx, urls, atomic)
+
+	aliases := make([]string, 0, len(urls))
+	for _, u := range urls {
+		aliases = append(aliases, u.Alias)
+	}
+	s.invalidate(aliases...)
+
+	return results, err
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string, owner string) error {
+	err := s.Repository.DeleteURL(ctx, alias, owner)
+	s.invalidate(alias)
+
+	return err
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error {
+	err := s.Repository.UpdateURL(ctx, alias, update, owner)
+	s.invalidate(alias)
+
+	return err
+}
+
+// DeleteExpired сбрасывает весь кэш, если что-то удалено: удаленные алиасы
+// неизвестны, а очистка идет редко.
+func (s *Storage) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	n, err := s.Repository.DeleteExpired(ctx, before)
+	if n > 0 {
+		s.purge()
+	}
+
+	return n, err
+}
+
+func (s *Storage) ArchiveExpired(ctx context.Context, before time.Time) (int64, error) {
+	n, err := s.Repository.ArchiveExpired(ctx, before)
+	if n > 0 {
+		s.purge()
+	}
+
+	return n, err
+}
+
+func (s *Storage) Stats() Stats {
+	s.mu.Lock()
+	entries := s.lru.Len()
+	s.mu.Unlock()
+
+	return Stats{
+		Hits:      s.hits.Load(),
+		Misses:    s.misses.Load(),
+		Evictions: s.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+func (s *Storage) lookup(key string) (entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return entry{}, false
+	}
+
+	e := elem.Value.(*entry)
+	if !s.now().Before(e.expiresAt) {
+		s.remove(elem)
+		return entry{}, false
+	}
+
+	s.lru.MoveToFront(elem)
+
+	return *e, true
+}
+
+func (s *Storage) store(generation uint64, e entry, ttl time.Duration) {
+	if ttl <= 0 || s.opts.Size <= 0 {
+		return
+	}
+
+	e.expiresAt = s.now().Add(ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation {
+		return
+	}
+
+	if elem, ok := s.entries[e.key]; ok {
+		*elem.Value.(*entry) = e
+		s.lru.MoveToFront(elem)
+
+		return
+	}
+
+	s.entries[e.key] = s.lru.PushFront(&e)
+
+	for s.lru.Len() > s.opts.Size {
+		s.remove(s.lru.Back())
+		s.evictions.Add(1)
+	}
+}
+
+func (s *Storage) invalidate(aliases ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+
+	for _, alias := range aliases {
+		if elem, ok := s.entries[cacheKey(alias)]; ok {
+			s.remove(elem)
+		}
+	}
+}
+
+func (s *Storage) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.entries = make(map[string]*list.Element, s.opts.Size)
+	s.lru.Init()
+}
+
+func (s *Storage) currentGeneration() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.generation
+}
+
+// remove вызывается под s.mu.
+func (s *Storage) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*entry).key)
+}
+
+// cacheKey приводит алиас к нижнему регистру: хранилища сравнивают алиасы
+// без учета регистра, и "Promo" со "promo" — одна ссылка.
+func cacheKey(alias string) string {
+	return strings.ToLower(alias)
+}
@@ -0,0 +1,301 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/storage/storagetest"
+)
+
+// countingRepo считает чтения ссылок из нижележащего хранилища.
+type countingRepo struct {
+	storage.Repository
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (r *countingRepo) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+
+	return r.Repository.GetURLExpiry(ctx, alias)
+}
+
+func (r *countingRepo) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.calls
+}
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newCached(t *testing.T, opts Options) (*Storage, *countingRepo, *fakeClock) {
+	t.Helper()
+
+	repo := &countingRepo{Repository: memory.New()}
+	clock := &fakeClock{now: time.Now()}
+
+	s := New(repo, opts)
+	s.now = clock.Now
+
+	return s, repo, clock
+}
+
+var testOptions = Options{Size: 100, TTL: time.Minute, NegativeTTL: 10 * time.Second}
+
+func TestStorageContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return New(memory.New(), testOptions)
+	})
+}
+
+func TestGetURL_Hit(t *testing.T) {
+	ctx := context.Background()
+	s, repo, _ := newCached(t, testOptions)
+
+	_, err := s.SaveURL(ctx, "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	for _, alias := range []string{"promo", "promo", "PROMO"} {
+		url, err := s.GetURL(ctx, alias)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", url)
+	}
+
+	assert.Equal(t, 1, repo.Calls())
+	assert.Equal(t, Stats{Hits: 2, Misses: 1, Entries: 1}, s.Stats())
+}
+
+func TestGetURL_TTL(t *testing.T) {
+	ctx := context.Background()
+	s, repo, clock := newCached(t, testOptions)
+
+	_, err := s.SaveURL(ctx, "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+
+	clock.now = clock.now.Add(testOptions.TTL - time.Second)
+	_, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.Calls())
+
+	clock.now = clock.now.Add(time.Second)
+	_, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.Calls())
+}
+
+// Ссылка, истекающая раньше TTL, не должна отдаваться из кэша после срока.
+func TestGetURL_LinkExpiry(t *testing.T) {
+	ctx := context.Background()
+	s, repo, clock := newCached(t, testOptions)
+
+	expiresAt := clock.now.Add(testOptions.TTL / 2)
+
+	_, err := s.SaveURL(ctx, "https://example.com", "promo", expiresAt, "")
+	require.NoError(t, err)
+
+	url, gotExpiresAt, err := s.GetURLExpiry(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+	assert.True(t, expiresAt.Equal(gotExpiresAt))
+
+	// Хранилище живет по настоящим часам, поэтому срок в нем сдвигается в
+	// прошлое напрямую, мимо кэша
+	past := time.Now().Add(-time.Second)
+	require.NoError(t, repo.Repository.UpdateURL(ctx, "promo", storage.URLUpdate{URL: "https://example.com", ExpiresAt: &past}, ""))
+
+	clock.now = expiresAt.Add(-time.Second)
+	_, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.Calls())
+
+	clock.now = expiresAt
+	_, err = s.GetURL(ctx, "promo")
+	require.ErrorIs(t, err, storage.ErrUrlExpired)
+	assert.Equal(t, 2, repo.Calls())
+}
+
+func TestGetURL_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	s, repo, clock := newCached(t, testOptions)
+
+	_, err := s.Repository.SaveURL(ctx, "https://example.com", "expired", time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	for range 3 {
+		_, err = s.GetURL(ctx, "missing")
+		require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+		_, err = s.GetURL(ctx, "expired")
+		require.ErrorIs(t, err, storage.ErrUrlExpired)
+	}
+
+	assert.Equal(t, 2, repo.Calls())
+
+	// Промах помнится меньше, чем найденная ссылка
+	clock.now = clock.now.Add(testOptions.NegativeTTL)
+
+	_, err = s.GetURL(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+	assert.Equal(t, 3, repo.Calls())
+}
+
+func TestGetURL_FailuresNotCached(t *testing.T) {
+	s, repo, _ := newCached(t, testOptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.GetURL(ctx, "promo")
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = s.GetURL(context.Background(), "promo")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	assert.Equal(t, 2, repo.Calls())
+}
+
+func TestInvalidation(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newCached(t, testOptions)
+
+	// Сохранение сбрасывает запомненный промах
+	_, err := s.GetURL(ctx, "promo")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.SaveURL(ctx, "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	url, err := s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+
+	// Изменение
+	require.NoError(t, s.UpdateURL(ctx, "PROMO", storage.URLUpdate{URL: "https://example.com/new"}, ""))
+
+	url, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", url)
+
+	// Удаление
+	require.NoError(t, s.DeleteURL(ctx, "Promo", ""))
+
+	_, err = s.GetURL(ctx, "promo")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	// Пакетное сохранение
+	_, err = s.SaveURLs(ctx, []storage.NewURL{{URL: "https://example.com/batch", Alias: "promo"}}, true)
+	require.NoError(t, err)
+
+	url, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/batch", url)
+}
+
+func TestInvalidation_ExpiredCleanup(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newCached(t, testOptions)
+
+	_, err := s.SaveURL(ctx, "https://example.com", "promo", time.Now().Add(time.Hour), "")
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+
+	n, err := s.DeleteExpired(ctx, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	_, err = s.GetURL(ctx, "promo")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+// Чтение, начатое до изменения ссылки, не должно вернуть в кэш старый адрес.
+func TestInvalidation_InFlightRead(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newCached(t, testOptions)
+
+	_, err := s.SaveURL(ctx, "https://example.com/old", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	generation := s.currentGeneration()
+
+	require.NoError(t, s.UpdateURL(ctx, "promo", storage.URLUpdate{URL: "https://example.com/new"}, ""))
+
+	s.store(generation, entry{key: "promo", url: "https://example.com/old"}, testOptions.TTL)
+
+	url, err := s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", url)
+}
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+	s, repo, _ := newCached(t, Options{Size: 2, TTL: time.Minute, NegativeTTL: time.Minute})
+
+	for _, alias := range []string{"a", "b", "a", "c"} {
+		_, err := s.GetURL(ctx, alias)
+		require.ErrorIs(t, err, storage.ErrUrlNotFound)
+	}
+
+	// "b" прочитан раньше всех и вытеснен, "a" остался
+	_, _ = s.GetURL(ctx, "a")
+	_, _ = s.GetURL(ctx, "b")
+
+	assert.Equal(t, 4, repo.Calls())
+	assert.Equal(t, Stats{Hits: 2, Misses: 4, Evictions: 2, Entries: 2}, s.Stats())
+}
+
+// BenchmarkGetURL сравнивает переходы по горячему набору алиасов напрямую
+// через sqlite и через кэш.
+func BenchmarkGetURL(b *testing.B) {
+	const aliases = 1000
+
+	repo, err := sqlite.New(filepath.Join(b.TempDir(), "storage.db"), sqlite.Options{QueryTimeout: time.Second})
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = repo.Close() })
+
+	for i := range aliases {
+		_, err := repo.SaveURL(context.Background(), "https://example.com", fmt.Sprintf("alias%d", i), time.Time{}, "")
+		require.NoError(b, err)
+	}
+
+	for _, bench := range []struct {
+		name string
+		repo storage.Repository
+	}{
+		{name: "sqlite", repo: repo},
+		{name: "cached", repo: New(repo, Options{Size: aliases, TTL: time.Minute, NegativeTTL: time.Minute})},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, err := bench.repo.GetURL(context.Background(), fmt.Sprintf("alias%d", i%aliases)); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
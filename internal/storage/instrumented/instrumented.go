@@ -0,0 +1,63 @@
+// Package instrumented замеряет время и ошибки основных операций хранилища:
+// сохранения, чтения и удаления ссылок.
+package instrumented
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+// Observer получает результат каждой замеренной операции.
+type Observer interface {
+	ObserveStorage(operation string, duration time.Duration, err error)
+}
+
+// Storage оборачивает хранилище. Остальные методы вызываются без замеров.
+type Storage struct {
+	storage.Repository
+	observer Observer
+}
+
+func New(repo storage.Repository, observer Observer) *Storage {
+	return &Storage{Repository: repo, observer: observer}
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	start := time.Now()
+
+	id, err := s.Repository.SaveURL(ctx, urlToSave, alias, expiresAt, owner)
+	s.observer.ObserveStorage("save_url", time.Since(start), err)
+
+	return id, err
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+	start := time.Now()
+
+	url, err := s.Repository.GetURL(ctx, alias)
+	s.observer.ObserveStorage("get_url", time.Since(start), err)
+
+	return url, err
+}
+
+// GetURLExpiry — то же чтение ссылки, что и GetURL, и замеряется под тем же именем.
+func (s *Storage) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	start := time.Now()
+
+	url, expiresAt, err := s.Repository.GetURLExpiry(ctx, alias)
+	s.observer.ObserveStorage("get_url", time.Since(start), err)
+
+	return url, expiresAt, err
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string, owner string) error {
+	start := time.Now()
+
+	err := s.Repository.DeleteURL(ctx, alias, owner)
+	s.observer.ObserveStorage("delete_url", time.Since(start), err)
+
+	return err
+}
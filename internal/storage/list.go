@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Порядок выдачи ListURLs.
+const (
+	// ListSortCreated — сначала новые ссылки.
+	ListSortCreated = "created"
+	// ListSortAlias — по алиасу без учета регистра.
+	ListSortAlias = "alias"
+	// ListSortClicks — сначала ссылки с наибольшим числом переходов.
+	ListSortClicks = "clicks"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListOptions — параметры выборки ссылок. Фильтры Alias и Domain ищут подстроку
+// без учета регистра в алиасе и в домене целевого адреса соответственно.
+// Непустой Owner оставляет в выборке только ссылки этого владельца.
+type ListOptions struct {
+	Sort   string
+	Alias  string
+	Domain string
+	Owner  string
+	Limit  int
+	Cursor string
+}
+
+// URLPage — страница выборки. Пустой NextCursor означает, что страница последняя.
+type URLPage struct {
+	URLs       []URLStats
+	NextCursor string
+}
+
+// Cursor — позиция в выдаче: ключ сортировки последней строки страницы и ее id.
+type Cursor struct {
+	Sort   string `json:"s"`
+	ID     int64  `json:"i"`
+	Alias  string `json:"a,omitempty"`
+	Clicks int64  `json:"c,omitempty"`
+}
+
+// Normalize подставляет значения по умолчанию, ограничивает Limit
+// и раскодирует курсор. Для первой страницы курсор равен nil.
+func (o ListOptions) Normalize() (ListOptions, *Cursor, error) {
+	switch o.Sort {
+	case "":
+		o.Sort = ListSortCreated
+	case ListSortCreated, ListSortAlias, ListSortClicks:
+	default:
+		return o, nil, fmt.Errorf("unknown sort %q", o.Sort)
+	}
+
+	if o.Limit <= 0 {
+		o.Limit = DefaultListLimit
+	}
+	if o.Limit > MaxListLimit {
+		o.Limit = MaxListLimit
+	}
+
+	if o.Cursor == "" {
+		return o, nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return o, nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err = json.Unmarshal(raw, &cursor); err != nil || cursor.Sort != o.Sort {
+		return o, nil, ErrInvalidCursor
+	}
+
+	return o, &cursor, nil
+}
+
+// EncodeCursor возвращает курсор, указывающий на строку с заданным id.
+func EncodeCursor(sort string, id int64, last URLStats) string {
+	raw, _ := json.Marshal(Cursor{
+		Sort:   sort,
+		ID:     id,
+		Alias:  last.Alias,
+		Clicks: last.Clicks,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// URLDomain возвращает домен адреса в нижнем регистре, по нему работает фильтр Domain.
+func URLDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// ContainsPattern строит шаблон LIKE для поиска подстроки. Спецсимволы
+// экранируются обратной косой чертой, запрос должен использовать ESCAPE '\'.
+func ContainsPattern(substr string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(substr)
+
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,616 @@
+// Package memory реализует storage.Repository в памяти процесса.
+// Подходит для тестов и эфемерных инсталляций: данные теряются при перезапуске.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+type Storage struct {
+	mu       sync.RWMutex
+	lastID   int64
+	urls     map[string]record
+	archived []record
+	events   []storage.ClickEvent
+	apiKeys  []apiKey
+}
+
+type apiKey struct {
+	storage.APIKey
+	hash string
+}
+
+type record struct {
+	id             int64
+	alias          string
+	url            string
+	expiresAt      time.Time
+	createdAt      time.Time
+	clicks         int64
+	lastAccessedAt time.Time
+	owner          string
+}
+
+func New() *Storage {
+	return &Storage{urls: make(map[string]record)}
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.memory.Ping"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+// CheckSchema всегда успешен: у хранилища в памяти нет схемы.
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	return nil
+}
+
+// Close ничего не делает: данные в памяти живут до конца процесса.
+func (s *Storage) Close() error {
+	return nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	const op = "storage.memory.SaveURL"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	key := aliasKey(alias)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.urls[key]; ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUrlExist)
+	}
+
+	s.lastID++
+	s.urls[key] = record{
+		id:        s.lastID,
+		alias:     alias,
+		url:       urlToSave,
+		expiresAt: expiresAt,
+		createdAt: time.Now(),
+		owner:     owner,
+	}
+
+	return s.lastID, nil
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.NewURL, atomic bool) ([]storage.SaveResult, error) {
+	const op = "storage.memory.SaveURLs"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]storage.SaveResult, len(urls))
+	added := make(map[string]record, len(urls))
+	lastID := s.lastID
+	now := time.Now()
+
+	for i, u := range urls {
+		key := aliasKey(u.Alias)
+
+		if _, ok := s.urls[key]; ok {
+			results[i].Err = storage.ErrUrlExist
+			continue
+		}
+		if _, ok := added[key]; ok {
+			results[i].Err = storage.ErrUrlExist
+			continue
+		}
+
+		lastID++
+		added[key] = record{
+			id:        lastID,
+			alias:     u.Alias,
+			url:       u.URL,
+			expiresAt: u.ExpiresAt,
+			createdAt: now,
+			owner:     u.Owner,
+		}
+		results[i].ID = lastID
+	}
+
+	if atomic && storage.Failed(results) {
+		for i := range results {
+			results[i].ID = 0
+		}
+
+		return results, nil
+	}
+
+	for key, rec := range added {
+		s.urls[key] = rec
+	}
+	s.lastID = lastID
+
+	return results, nil
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+	url, _, err := s.GetURLExpiry(ctx, alias)
+
+	return url, err
+}
+
+func (s *Storage) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	const op = "storage.memory.GetURLExpiry"
+
+	if err := ctx.Err(); err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.urls[aliasKey(alias)]
+	if !ok {
+		return "", time.Time{}, storage.ErrUrlNotFound
+	}
+
+	if rec.expired(time.Now()) {
+		return "", time.Time{}, storage.ErrUrlExpired
+	}
+
+	return rec.url, rec.expiresAt, nil
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string, owner string) error {
+	const op = "storage.memory.DeleteURL"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	key := aliasKey(alias)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.urls[key]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	}
+	if !rec.ownedBy(owner) {
+		return fmt.Errorf("%s: %w", op, storage.ErrUrlForbidden)
+	}
+
+	delete(s.urls, key)
+
+	return nil
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error {
+	const op = "storage.memory.UpdateURL"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	key := aliasKey(alias)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.urls[key]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	}
+	if !rec.ownedBy(owner) {
+		return fmt.Errorf("%s: %w", op, storage.ErrUrlForbidden)
+	}
+
+	rec.url = update.URL
+	if update.ExpiresAt != nil {
+		rec.expiresAt = *update.ExpiresAt
+	}
+
+	s.urls[key] = rec
+
+	return nil
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.memory.DeleteExpired"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+
+	for key, rec := range s.urls {
+		if rec.expired(before) {
+			delete(s.urls, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) ArchiveExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.memory.ArchiveExpired"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var archived int64
+
+	for key, rec := range s.urls {
+		if rec.expired(before) {
+			s.archived = append(s.archived, rec)
+			delete(s.urls, key)
+			archived++
+		}
+	}
+
+	return archived, nil
+}
+
+func (s *Storage) AddClicks(ctx context.Context, clicks []storage.ClickCount) error {
+	const op = "storage.memory.AddClicks"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		key := aliasKey(click.Alias)
+
+		rec, ok := s.urls[key]
+		if !ok {
+			continue
+		}
+
+		rec.clicks += click.Count
+		rec.lastAccessedAt = click.LastAccessedAt
+		s.urls[key] = rec
+	}
+
+	return nil
+}
+
+func (s *Storage) RestoreStats(ctx context.Context, stats storage.URLStats) error {
+	const op = "storage.memory.RestoreStats"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	key := aliasKey(stats.Alias)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.urls[key]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	}
+
+	rec.clicks = stats.Clicks
+	if !stats.CreatedAt.IsZero() {
+		rec.createdAt = stats.CreatedAt
+	}
+	if !stats.LastAccessedAt.IsZero() {
+		rec.lastAccessedAt = stats.LastAccessedAt
+	}
+
+	s.urls[key] = rec
+
+	return nil
+}
+
+func (s *Storage) GetStats(ctx context.Context, alias string) (storage.URLStats, error) {
+	const op = "storage.memory.GetStats"
+
+	if err := ctx.Err(); err != nil {
+		return storage.URLStats{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.urls[aliasKey(alias)]
+	if !ok {
+		return storage.URLStats{}, storage.ErrUrlNotFound
+	}
+
+	return rec.stats(), nil
+}
+
+func (s *Storage) ListURLs(ctx context.Context, opts storage.ListOptions) (storage.URLPage, error) {
+	const op = "storage.memory.ListURLs"
+
+	opts, cursor, err := opts.Normalize()
+	if err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	aliasFilter := strings.ToLower(opts.Alias)
+	domainFilter := strings.ToLower(opts.Domain)
+
+	// less задает порядок выдачи: true, если a идет раньше b
+	var less func(a, b record) bool
+
+	switch opts.Sort {
+	case storage.ListSortAlias:
+		less = func(a, b record) bool { return aliasKey(a.alias) < aliasKey(b.alias) }
+	case storage.ListSortClicks:
+		less = func(a, b record) bool {
+			if a.clicks != b.clicks {
+				return a.clicks > b.clicks
+			}
+
+			return a.id > b.id
+		}
+	default:
+		less = func(a, b record) bool { return a.id > b.id }
+	}
+
+	var after record
+	if cursor != nil {
+		after = record{id: cursor.ID, alias: cursor.Alias, clicks: cursor.Clicks}
+	}
+
+	var recs []record
+
+	for key, rec := range s.urls {
+		if !strings.Contains(key, aliasFilter) ||
+			!strings.Contains(storage.URLDomain(rec.url), domainFilter) ||
+			!rec.ownedBy(opts.Owner) {
+			continue
+		}
+
+		if cursor != nil && !less(after, rec) {
+			continue
+		}
+
+		recs = append(recs, rec)
+	}
+
+	sort.Slice(recs, func(i, j int) bool { return less(recs[i], recs[j]) })
+
+	var page storage.URLPage
+
+	for i, rec := range recs {
+		if i == opts.Limit {
+			last := page.URLs[len(page.URLs)-1]
+			page.NextCursor = storage.EncodeCursor(opts.Sort, recs[i-1].id, last)
+			break
+		}
+
+		page.URLs = append(page.URLs, rec.stats())
+	}
+
+	return page, nil
+}
+
+func (s *Storage) ForEachURL(ctx context.Context, owner string, fn func(storage.URLStats) error) error {
+	const op = "storage.memory.ForEachURL"
+
+	// Снимок берется под блокировкой, fn вызывается без нее, чтобы медленный
+	// получатель не блокировал запись
+	s.mu.RLock()
+	recs := make([]record, 0, len(s.urls))
+	for _, rec := range s.urls {
+		if owner != "" && rec.owner != owner {
+			continue
+		}
+
+		recs = append(recs, rec)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(recs, func(i, j int) bool { return recs[i].id < recs[j].id })
+
+	for _, rec := range recs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+
+		if err := fn(rec.stats()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	const op = "storage.memory.SaveClickEvents"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, events...)
+
+	return nil
+}
+
+func (s *Storage) ClickTimeSeries(
+	ctx context.Context,
+	alias string,
+	from, to time.Time,
+	bucket time.Duration,
+) ([]storage.ClickBucket, error) {
+	const op = "storage.memory.ClickTimeSeries"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := aliasKey(alias)
+	size := int64(bucket / time.Second)
+	counts := make(map[int64]int64)
+
+	for _, event := range s.events {
+		ts := event.Time.Unix()
+		if aliasKey(event.Alias) != key || ts < from.Unix() || ts >= to.Unix() {
+			continue
+		}
+
+		counts[ts/size*size]++
+	}
+
+	buckets := make([]storage.ClickBucket, 0, len(counts))
+	for start, clicks := range counts {
+		buckets = append(buckets, storage.ClickBucket{Start: time.Unix(start, 0).UTC(), Clicks: clicks})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+
+	return buckets, nil
+}
+
+func (s *Storage) CreateAPIKey(ctx context.Context, user, role, prefix, hash string) (storage.APIKey, error) {
+	const op = "storage.memory.CreateAPIKey"
+
+	if err := ctx.Err(); err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := storage.APIKey{
+		ID:        int64(len(s.apiKeys)) + 1,
+		User:      user,
+		Role:      role,
+		Prefix:    prefix,
+		CreatedAt: time.Now(),
+	}
+
+	s.apiKeys = append(s.apiKeys, apiKey{APIKey: key, hash: hash})
+
+	return key, nil
+}
+
+func (s *Storage) FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.memory.FindAPIKey"
+
+	if err := ctx.Err(); err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.apiKeys {
+		if key.hash == hash && key.RevokedAt.IsZero() {
+			return key.APIKey, nil
+		}
+	}
+
+	return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	const op = "storage.memory.ListAPIKeys"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]storage.APIKey, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		keys = append(keys, key.APIKey)
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.memory.RevokeAPIKey"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > int64(len(s.apiKeys)) || !s.apiKeys[id-1].RevokedAt.IsZero() {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	s.apiKeys[id-1].RevokedAt = time.Now()
+
+	return nil
+}
+
+// expired сообщает, истекла ли ссылка к моменту at.
+func (r record) expired(at time.Time) bool {
+	return !r.expiresAt.IsZero() && !r.expiresAt.After(at)
+}
+
+func (r record) stats() storage.URLStats {
+	return storage.URLStats{
+		Alias:          r.alias,
+		URL:            r.url,
+		Clicks:         r.clicks,
+		CreatedAt:      r.createdAt,
+		LastAccessedAt: r.lastAccessedAt,
+		ExpiresAt:      r.expiresAt,
+		Owner:          r.owner,
+	}
+}
+
+// ownedBy сообщает, доступна ли ссылка владельцу owner. Пустой owner — без ограничения.
+func (r record) ownedBy(owner string) bool {
+	return owner == "" || r.owner == owner
+}
+
+// aliasKey повторяет семантику COLLATE NOCASE из sqlite: алиасы сравниваются без учета регистра.
+func aliasKey(alias string) string {
+	return strings.ToLower(alias)
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/storagetest"
+)
+
+func TestStorageContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return memory.New()
+	})
+}
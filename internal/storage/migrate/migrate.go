@@ -0,0 +1,324 @@
+// Package migrate применяет версионные миграции схемы, встроенные в бинарник.
+//
+// Миграции — это пары файлов NNNN_name.up.sql и NNNN_name.down.sql.
+// Примененные версии хранятся в таблице schema_version.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrNoDownMigration = errors.New("down migration is missing")
+	ErrPending         = errors.New("schema has pending migrations")
+)
+
+var fileNameRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Options struct {
+	// Lock захватывает блокировку, общую для всех процессов с этой базой, и
+	// возвращает функцию ее снятия. Up и Down держат ее, пока читают версию
+	// схемы и применяют миграции, чтобы инстансы, запущенные одновременно,
+	// не применяли одну миграцию дважды. nil — без блокировки.
+	Lock func() (unlock func() error, err error)
+	// VersionTableQuery — запрос к каталогу базы, возвращающий число таблиц
+	// schema_version: 0 или 1. По нему Version, Pending и Planned отличают
+	// пустую базу от сбоя, ничего в ней не создавая. Пусто — таблица
+	// считается существующей.
+	VersionTableQuery string
+}
+
+type Migrator struct {
+	db                *sql.DB
+	migrations        []Migration
+	lock              func() (func() error, error)
+	versionTableQuery string
+}
+
+// New читает миграции из корня fsys. Запросов к базе не выполняет.
+func New(db *sql.DB, fsys fs.FS, opts Options) (*Migrator, error) {
+	const op = "storage.migrate.New"
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileNameRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s: unexpected migration file %q", op, entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d has different names: %q and %q", op, version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%s: version %d has no up migration", op, m.Version)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{db: db, migrations: migrations, lock: opts.Lock, versionTableQuery: opts.VersionTableQuery}, nil
+}
+
+// Version возвращает последнюю примененную версию схемы (0 — пустая база).
+// Только читает базу, поэтому годится для migrate -dry-run: таблицы
+// schema_version нет — версия нулевая.
+func (m *Migrator) Version() (int, error) {
+	const op = "storage.migrate.Version"
+
+	if m.versionTableQuery != "" {
+		var tables int
+
+		if err := m.db.QueryRow(m.versionTableQuery).Scan(&tables); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if tables == 0 {
+			return 0, nil
+		}
+	}
+
+	var version sql.NullInt64
+
+	if err := m.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(version.Int64), nil
+}
+
+// Check возвращает ErrPending, если к базе применены не все миграции.
+// В отличие от Version учитывает ctx, поэтому подходит для периодических
+// проверок готовности, а база без таблицы версий для него — ошибка.
+func (m *Migrator) Check(ctx context.Context) error {
+	const op = "storage.migrate.Check"
+
+	var version sql.NullInt64
+
+	if err := m.db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_version").Scan(&version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(m.migrations) > 0 && int(version.Int64) < m.migrations[len(m.migrations)-1].Version {
+		return fmt.Errorf("%s: version %d: %w", op, version.Int64, ErrPending)
+	}
+
+	return nil
+}
+
+// Pending возвращает миграции, которые будут применены вызовом Up. Как и
+// Version, только читает базу.
+func (m *Migrator) Pending() ([]Migration, error) {
+	const op = "storage.migrate.Pending"
+
+	version, err := m.Version()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var pending []Migration
+
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up применяет все ожидающие миграции, каждую в своей транзакции. Список
+// ожидающих читается уже под блокировкой: пока этот процесс ее ждал, миграции
+// мог применить другой.
+func (m *Migrator) Up() (_ []Migration, err error) {
+	const op = "storage.migrate.Up"
+
+	unlock, err := m.acquire()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", op, unlockErr))
+		}
+	}()
+
+	if err = m.ensureVersionTable(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i, migration := range pending {
+		err := m.apply(migration.Up,
+			"INSERT INTO schema_version(version) VALUES ("+strconv.Itoa(migration.Version)+")")
+		if err != nil {
+			return pending[:i], fmt.Errorf("%s: version %d: %w", op, migration.Version, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// Planned возвращает миграции, которые будут откачены вызовом Down(steps).
+// Как и Version, только читает базу.
+func (m *Migrator) Planned(steps int) ([]Migration, error) {
+	const op = "storage.migrate.Planned"
+
+	version, err := m.Version()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var planned []Migration
+
+	for i := len(m.migrations) - 1; i >= 0 && len(planned) < steps; i-- {
+		if m.migrations[i].Version <= version {
+			planned = append(planned, m.migrations[i])
+		}
+	}
+
+	return planned, nil
+}
+
+// Down откатывает steps последних примененных миграций. Как и Up, работает
+// под блокировкой.
+func (m *Migrator) Down(steps int) (_ []Migration, err error) {
+	const op = "storage.migrate.Down"
+
+	unlock, err := m.acquire()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", op, unlockErr))
+		}
+	}()
+
+	if err = m.ensureVersionTable(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	planned, err := m.Planned(steps)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i, migration := range planned {
+		if migration.Down == "" {
+			return planned[:i], fmt.Errorf("%s: version %d: %w", op, migration.Version, ErrNoDownMigration)
+		}
+
+		err := m.apply(migration.Down,
+			"DELETE FROM schema_version WHERE version = "+strconv.Itoa(migration.Version))
+		if err != nil {
+			return planned[:i], fmt.Errorf("%s: version %d: %w", op, migration.Version, err)
+		}
+	}
+
+	return planned, nil
+}
+
+func (m *Migrator) acquire() (func() error, error) {
+	if m.lock == nil {
+		return func() error { return nil }, nil
+	}
+
+	unlock, err := m.lock()
+	if err != nil {
+		return nil, fmt.Errorf("lock: %w", err)
+	}
+
+	return func() error {
+		if err := unlock(); err != nil {
+			return fmt.Errorf("unlock: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_version(
+	    version INTEGER PRIMARY KEY,
+	    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);
+	`)
+
+	return err
+}
+
+func (m *Migrator) apply(migrationSQL, versionSQL string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.Exec(migrationSQL); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(versionSQL); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,249 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage/migrate"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a(id INTEGER);")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b(id INTEGER); CREATE TABLE c(id INTEGER);")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE c; DROP TABLE b;")},
+}
+
+// testOptions — как у хранилища sqlite: версию можно читать без таблицы schema_version.
+var testOptions = migrate.Options{
+	VersionTableQuery: "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'",
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	require.NoError(t, err)
+
+	return count > 0
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := migrate.New(db, testMigrations, testOptions)
+	require.NoError(t, err)
+
+	pending, err := m.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, 1, pending[0].Version)
+	require.Equal(t, "create_a", pending[0].Name)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+	require.True(t, tableExists(t, db, "c"))
+
+	applied, err = m.Up()
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	planned, err := m.Planned(1)
+	require.NoError(t, err)
+	require.Len(t, planned, 1)
+	require.Equal(t, 2, planned[0].Version)
+
+	reverted, err := m.Down(1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	require.False(t, tableExists(t, db, "b"))
+	require.True(t, tableExists(t, db, "a"))
+
+	version, err = m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	_, err = m.Down(10)
+	require.NoError(t, err)
+	require.False(t, tableExists(t, db, "a"))
+}
+
+// Version, Pending и Planned нужны migrate -dry-run и не должны менять базу.
+func TestMigrator_ReadsWithoutSideEffects(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := migrate.New(db, testMigrations, testOptions)
+	require.NoError(t, err)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	pending, err := m.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	planned, err := m.Planned(1)
+	require.NoError(t, err)
+	require.Empty(t, planned)
+
+	require.False(t, tableExists(t, db, "schema_version"))
+}
+
+func TestMigrator_Check(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := migrate.New(db, testMigrations, migrate.Options{})
+	require.NoError(t, err)
+
+	// Таблицы версий еще нет, и Check ее не создает
+	require.Error(t, m.Check(context.Background()))
+	require.False(t, tableExists(t, db, "schema_version"))
+
+	_, err = m.Up()
+	require.NoError(t, err)
+	require.NoError(t, m.Check(context.Background()))
+
+	_, err = m.Down(1)
+	require.NoError(t, err)
+	require.ErrorIs(t, m.Check(context.Background()), migrate.ErrPending)
+}
+
+// Пока Up ждал блокировку, другой инстанс применил миграции: Up должен
+// увидеть это и ничего не применять повторно.
+func TestMigrator_UpRereadsPendingUnderLock(t *testing.T) {
+	db := newTestDB(t)
+
+	other, err := migrate.New(db, testMigrations, migrate.Options{})
+	require.NoError(t, err)
+
+	var locked, unlocked int
+
+	m, err := migrate.New(db, testMigrations, migrate.Options{
+		Lock: func() (func() error, error) {
+			locked++
+
+			_, err := other.Up()
+			require.NoError(t, err)
+
+			return func() error {
+				unlocked++
+				return nil
+			}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	_, err = m.Down(1)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, locked)
+	require.Equal(t, 2, unlocked)
+}
+
+func TestMigrator_LockError(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := migrate.New(db, testMigrations, migrate.Options{
+		Lock: func() (func() error, error) {
+			return nil, errors.New("lock timeout")
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.Error(t, err)
+	require.False(t, tableExists(t, db, "a"))
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := migrate.New(db, fstest.MapFS{
+		"0001_ok.up.sql":     {Data: []byte("CREATE TABLE ok(id INTEGER);")},
+		"0002_broken.up.sql": {Data: []byte("CREATE TABLE broken(id INTEGER); NOT SQL;")},
+	}, migrate.Options{})
+	require.NoError(t, err)
+
+	applied, err := m.Up()
+	require.Error(t, err)
+	require.Len(t, applied, 1)
+	require.False(t, tableExists(t, db, "broken"))
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+}
+
+func TestMigrator_DownWithoutDownFile(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := migrate.New(db, fstest.MapFS{
+		"0001_only_up.up.sql": {Data: []byte("CREATE TABLE a(id INTEGER);")},
+	}, migrate.Options{})
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	_, err = m.Down(1)
+	require.ErrorIs(t, err, migrate.ErrNoDownMigration)
+}
+
+func TestNew_InvalidFiles(t *testing.T) {
+	cases := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "Unexpected file name",
+			fsys: fstest.MapFS{"create.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "Missing up migration",
+			fsys: fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "Different names for one version",
+			fsys: fstest.MapFS{
+				"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"0001_b.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := migrate.New(nil, tc.fsys, migrate.Options{})
+			require.Error(t, err)
+		})
+	}
+}
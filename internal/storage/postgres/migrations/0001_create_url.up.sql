@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS url(
+    id BIGSERIAL PRIMARY KEY,
+    alias TEXT NOT NULL,
+    url TEXT NOT NULL);
+
+-- Уникальность alias без учета регистра, как COLLATE NOCASE в sqlite
+CREATE UNIQUE INDEX IF NOT EXISTS idx_url_alias ON url (lower(alias));
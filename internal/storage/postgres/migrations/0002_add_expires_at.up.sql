@@ -0,0 +1,10 @@
+ALTER TABLE url ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_url_expires_at ON url(expires_at);
+
+CREATE TABLE IF NOT EXISTS url_archive(
+    id BIGSERIAL PRIMARY KEY,
+    alias TEXT NOT NULL,
+    url TEXT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    archived_at TIMESTAMPTZ NOT NULL);
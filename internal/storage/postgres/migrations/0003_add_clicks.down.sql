@@ -0,0 +1,5 @@
+ALTER TABLE url DROP COLUMN IF EXISTS last_accessed_at;
+
+ALTER TABLE url DROP COLUMN IF EXISTS clicks;
+
+ALTER TABLE url DROP COLUMN IF EXISTS created_at;
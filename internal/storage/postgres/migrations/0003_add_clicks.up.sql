@@ -0,0 +1,8 @@
+-- Существующие строки остаются без created_at, значение по умолчанию — только для новых
+ALTER TABLE url ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+
+ALTER TABLE url ALTER COLUMN created_at SET DEFAULT now();
+
+ALTER TABLE url ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE url ADD COLUMN IF NOT EXISTS last_accessed_at TIMESTAMPTZ;
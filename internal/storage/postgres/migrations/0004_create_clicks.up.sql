@@ -0,0 +1,11 @@
+-- clicked_at хранится как unix-время в секундах, чтобы группировать события по интервалам
+CREATE TABLE IF NOT EXISTS clicks(
+    id BIGSERIAL PRIMARY KEY,
+    alias TEXT NOT NULL,
+    clicked_at BIGINT NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_hash TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '');
+
+CREATE INDEX IF NOT EXISTS idx_clicks_alias_clicked_at ON clicks(lower(alias), clicked_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_clicks;
+
+ALTER TABLE url DROP COLUMN IF EXISTS domain;
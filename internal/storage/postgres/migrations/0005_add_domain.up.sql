@@ -0,0 +1,7 @@
+-- Домен целевого адреса для фильтра в списке ссылок. Для новых строк его
+-- заполняет приложение, существующие разбираются регулярным выражением.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS domain TEXT NOT NULL DEFAULT '';
+
+UPDATE url SET domain = coalesce(lower(substring(url FROM '^[^:/?#]+://(?:[^@/?#]*@)?([^:/?#]+)')), '');
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks ON url(clicks, id);
@@ -0,0 +1,843 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Коды ошибок PostgreSQL, см. https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeUniqueViolation     = "23505"
+	codeForeignKeyViolation = "23503"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+type Storage struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+type Options struct {
+	// QueryTimeout ограничивает время одного запроса к базе. 0 — без ограничения.
+	QueryTimeout time.Duration
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// New подключается к базе и применяет к ней все ожидающие миграции.
+func New(dsn string, opts Options) (*Storage, error) {
+	const op = "storage.postgres.New"
+
+	s, err := Open(dsn, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		_ = s.db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err = migrator.Up(); err != nil {
+		_ = s.db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Open подключается к базе без применения миграций.
+func Open(dsn string, opts Options) (*Storage, error) {
+	const op = "storage.postgres.Open"
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db, queryTimeout: opts.QueryTimeout}, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgres.Ping"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	const op = "storage.postgres.CheckSchema"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = migrator.Check(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+// Close закрывает пул соединений, дожидаясь завершения начатых запросов.
+func (s *Storage) Close() error {
+	const op = "storage.postgres.Close"
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	const op = "storage.postgres.Migrator"
+
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := migrate.New(s.db, fsys, migrate.Options{
+		Lock:              s.lockMigrations,
+		VersionTableQuery: "SELECT COUNT(*) FROM pg_tables WHERE schemaname = current_schema() AND tablename = 'schema_version'",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return m, nil
+}
+
+// migrationLockID — ключ pg_advisory_lock, под которым инстансы, делящие
+// базу, по очереди применяют миграции.
+const migrationLockID = 7_305_214_986
+
+// lockMigrations берет advisory lock на отдельном соединении: блокировка
+// принадлежит сессии, и миграции, идущие через другие соединения пула,
+// выполняются под ней.
+func (s *Storage) lockMigrations() (func() error, error) {
+	ctx := context.Background()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+		if err != nil {
+			// Соединение с неснятой блокировкой нельзя возвращать в пул:
+			// ErrBadConn из Raw закрывает его
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			return err
+		}
+
+		return conn.Close()
+	}, nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	const op = "storage.postgres.SaveURL"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	var id int64
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO url(url, alias, expires_at, domain, owner) VALUES($1, $2, $3, $4, $5) RETURNING id",
+		urlToSave, alias, nullTime(expiresAt), storage.URLDomain(urlToSave), owner,
+	).Scan(&id)
+	if err != nil {
+		if pgErrCode(err) == codeUniqueViolation {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUrlExist)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.NewURL, atomic bool) ([]storage.SaveResult, error) {
+	const op = "storage.postgres.SaveURLs"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Ошибка в PostgreSQL прерывает всю транзакцию, поэтому конфликт алиаса
+	// обрабатывается через ON CONFLICT: строка не вставляется и id не возвращается
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO url(url, alias, expires_at, domain, owner) VALUES($1, $2, $3, $4, $5)
+	ON CONFLICT DO NOTHING RETURNING id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer stmt.Close()
+
+	results := make([]storage.SaveResult, len(urls))
+
+	for i, u := range urls {
+		err = stmt.QueryRowContext(ctx, u.URL, u.Alias, nullTime(u.ExpiresAt), storage.URLDomain(u.URL), u.Owner).
+			Scan(&results[i].ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			results[i].Err = storage.ErrUrlExist
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+	}
+
+	if atomic && storage.Failed(results) {
+		for i := range results {
+			results[i].ID = 0
+		}
+
+		return results, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return results, nil
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+	url, _, err := s.GetURLExpiry(ctx, alias)
+
+	return url, err
+}
+
+func (s *Storage) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	const op = "storage.postgres.GetURLExpiry"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	var url string
+	var expiresAt sql.NullTime
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT url, expires_at FROM url WHERE lower(alias) = lower($1)", alias,
+	).Scan(&url, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", time.Time{}, storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return "", time.Time{}, storage.ErrUrlExpired
+	}
+
+	return url, expiresAt.Time, nil
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string, owner string) error {
+	const op = "storage.postgres.DeleteURL"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM url WHERE lower(alias) = lower($1) AND ($2 = '' OR owner = $2)", alias, owner)
+	if err != nil {
+		if pgErrCode(err) == codeForeignKeyViolation {
+			return fmt.Errorf("%s: %w", op, storage.ErrUrlHasReferences)
+		}
+
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, s.missingOrForeign(ctx, alias, owner))
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error {
+	const op = "storage.postgres.UpdateURL"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := "UPDATE url SET url = $1, domain = $3 WHERE lower(alias) = lower($2) AND ($4 = '' OR owner = $4)"
+	args := []any{update.URL, alias, storage.URLDomain(update.URL), owner}
+
+	if update.ExpiresAt != nil {
+		query = "UPDATE url SET url = $1, domain = $3, expires_at = $5 WHERE lower(alias) = lower($2) AND ($4 = '' OR owner = $4)"
+		args = append(args, nullTime(*update.ExpiresAt))
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, s.missingOrForeign(ctx, alias, owner))
+	}
+
+	return nil
+}
+
+// missingOrForeign объясняет, почему запрос с ограничением по владельцу не затронул
+// ни одной строки: ссылки нет (ErrUrlNotFound) или она чужая (ErrUrlForbidden).
+func (s *Storage) missingOrForeign(ctx context.Context, alias, owner string) error {
+	if owner == "" {
+		return storage.ErrUrlNotFound
+	}
+
+	var found int
+
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM url WHERE lower(alias) = lower($1)", alias).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return storage.ContextError(ctx, err)
+	}
+
+	return storage.ErrUrlForbidden
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.DeleteExpired"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM url WHERE expires_at <= $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) ArchiveExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.ArchiveExpired"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+	WITH moved AS (
+	    DELETE FROM url WHERE expires_at <= $1
+	    RETURNING alias, url, expires_at)
+	INSERT INTO url_archive(alias, url, expires_at, archived_at)
+	SELECT alias, url, expires_at, now() FROM moved`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	archived, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return archived, nil
+}
+
+func (s *Storage) AddClicks(ctx context.Context, clicks []storage.ClickCount) error {
+	const op = "storage.postgres.AddClicks"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, click := range clicks {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE url SET clicks = clicks + $1, last_accessed_at = $2 WHERE lower(alias) = lower($3)",
+			click.Count, nullTime(click.LastAccessedAt), click.Alias)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) RestoreStats(ctx context.Context, stats storage.URLStats) error {
+	const op = "storage.postgres.RestoreStats"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+	UPDATE url SET
+	    clicks = $1,
+	    created_at = COALESCE($2, created_at),
+	    last_accessed_at = COALESCE($3, last_accessed_at)
+	WHERE lower(alias) = lower($4)`,
+		stats.Clicks, nullTime(stats.CreatedAt), nullTime(stats.LastAccessedAt), stats.Alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetStats(ctx context.Context, alias string) (storage.URLStats, error) {
+	const op = "storage.postgres.GetStats"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	var (
+		stats                                storage.URLStats
+		createdAt, lastAccessedAt, expiresAt sql.NullTime
+	)
+
+	err := s.db.QueryRowContext(ctx, `
+	SELECT alias, url, clicks, created_at, last_accessed_at, expires_at, owner
+	FROM url WHERE lower(alias) = lower($1)`, alias,
+	).Scan(&stats.Alias, &stats.URL, &stats.Clicks, &createdAt, &lastAccessedAt, &expiresAt, &stats.Owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.URLStats{}, storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return storage.URLStats{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	stats.CreatedAt = createdAt.Time
+	stats.LastAccessedAt = lastAccessedAt.Time
+	stats.ExpiresAt = expiresAt.Time
+
+	return stats, nil
+}
+
+func (s *Storage) ListURLs(ctx context.Context, opts storage.ListOptions) (storage.URLPage, error) {
+	const op = "storage.postgres.ListURLs"
+
+	opts, cursor, err := opts.Normalize()
+	if err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	var (
+		where []string
+		args  []any
+	)
+
+	// arg добавляет значение в список аргументов и возвращает его плейсхолдер
+	arg := func(value any) string {
+		args = append(args, value)
+
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if opts.Alias != "" {
+		where = append(where, `lower(alias) LIKE lower(`+arg(storage.ContainsPattern(opts.Alias))+`) ESCAPE '\'`)
+	}
+	if opts.Domain != "" {
+		where = append(where, `domain LIKE `+arg(storage.ContainsPattern(strings.ToLower(opts.Domain)))+` ESCAPE '\'`)
+	}
+	if opts.Owner != "" {
+		where = append(where, "owner = "+arg(opts.Owner))
+	}
+
+	var order string
+
+	switch opts.Sort {
+	case storage.ListSortAlias:
+		order = "lower(alias)"
+		if cursor != nil {
+			where = append(where, "lower(alias) > lower("+arg(cursor.Alias)+")")
+		}
+	case storage.ListSortClicks:
+		order = "clicks DESC, id DESC"
+		if cursor != nil {
+			where = append(where, "(clicks, id) < ("+arg(cursor.Clicks)+", "+arg(cursor.ID)+")")
+		}
+	default:
+		order = "id DESC"
+		if cursor != nil {
+			where = append(where, "id < "+arg(cursor.ID))
+		}
+	}
+
+	query := "SELECT id, alias, url, clicks, created_at, last_accessed_at, expires_at, owner FROM url"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order + " LIMIT " + arg(opts.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var (
+		page storage.URLPage
+		ids  []int64
+	)
+
+	for rows.Next() {
+		var (
+			id                                   int64
+			stats                                storage.URLStats
+			createdAt, lastAccessedAt, expiresAt sql.NullTime
+		)
+
+		err = rows.Scan(&id, &stats.Alias, &stats.URL, &stats.Clicks, &createdAt, &lastAccessedAt, &expiresAt, &stats.Owner)
+		if err != nil {
+			return storage.URLPage{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		stats.CreatedAt = createdAt.Time
+		stats.LastAccessedAt = lastAccessedAt.Time
+		stats.ExpiresAt = expiresAt.Time
+
+		page.URLs = append(page.URLs, stats)
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	if len(page.URLs) > opts.Limit {
+		page.URLs = page.URLs[:opts.Limit]
+		page.NextCursor = storage.EncodeCursor(opts.Sort, ids[opts.Limit-1], page.URLs[opts.Limit-1])
+	}
+
+	return page, nil
+}
+
+func (s *Storage) ForEachURL(ctx context.Context, owner string, fn func(storage.URLStats) error) error {
+	const op = "storage.postgres.ForEachURL"
+
+	query := "SELECT alias, url, clicks, created_at, last_accessed_at, expires_at, owner FROM url"
+	var args []any
+
+	// Отдельный запрос, а не (owner = '' OR ...): так обход владельца идет по idx_url_owner
+	if owner != "" {
+		query += " WHERE owner = $1"
+		args = append(args, owner)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			stats                                storage.URLStats
+			createdAt, lastAccessedAt, expiresAt sql.NullTime
+		)
+
+		err = rows.Scan(&stats.Alias, &stats.URL, &stats.Clicks, &createdAt, &lastAccessedAt, &expiresAt, &stats.Owner)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		stats.CreatedAt = createdAt.Time
+		stats.LastAccessedAt = lastAccessedAt.Time
+		stats.ExpiresAt = expiresAt.Time
+
+		if err = fn(stats); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	const op = "storage.postgres.SaveClickEvents"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO clicks(alias, clicked_at, referrer, user_agent, ip_hash, request_id)
+	VALUES($1, $2, $3, $4, $5, $6)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		_, err = stmt.ExecContext(ctx,
+			event.Alias, event.Time.Unix(), event.Referrer, event.UserAgent, event.IPHash, event.RequestID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) ClickTimeSeries(
+	ctx context.Context,
+	alias string,
+	from, to time.Time,
+	bucket time.Duration,
+) ([]storage.ClickBucket, error) {
+	const op = "storage.postgres.ClickTimeSeries"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	size := int64(bucket / time.Second)
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT (clicked_at / $1) * $1 AS bucket, COUNT(*) FROM clicks
+	WHERE lower(alias) = lower($2) AND clicked_at >= $3 AND clicked_at < $4
+	GROUP BY bucket ORDER BY bucket`, size, alias, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var buckets []storage.ClickBucket
+
+	for rows.Next() {
+		var start, clicks int64
+
+		if err = rows.Scan(&start, &clicks); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		buckets = append(buckets, storage.ClickBucket{Start: time.Unix(start, 0).UTC(), Clicks: clicks})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return buckets, nil
+}
+
+func (s *Storage) CreateAPIKey(ctx context.Context, user, role, prefix, hash string) (storage.APIKey, error) {
+	const op = "storage.postgres.CreateAPIKey"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	key := storage.APIKey{User: user, Role: role, Prefix: prefix, CreatedAt: time.Now().UTC()}
+
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO api_keys(user_name, role, prefix, key_hash, created_at) VALUES($1, $2, $3, $4, $5) RETURNING id",
+		user, role, prefix, hash, key.CreatedAt,
+	).Scan(&key.ID)
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return key, nil
+}
+
+func (s *Storage) FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.postgres.FindAPIKey"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `
+	SELECT id, user_name, role, prefix, created_at, revoked_at
+	FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, hash)
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	const op = "storage.postgres.ListAPIKeys"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT id, user_name, role, prefix, created_at, revoked_at
+	FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var keys []storage.APIKey
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL",
+		time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// scanAPIKey читает строку api_keys в порядке id, user_name, role, prefix, created_at, revoked_at.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (storage.APIKey, error) {
+	var (
+		key       storage.APIKey
+		revokedAt sql.NullTime
+	)
+
+	if err := row.Scan(&key.ID, &key.User, &key.Role, &key.Prefix, &key.CreatedAt, &revokedAt); err != nil {
+		return storage.APIKey{}, err
+	}
+
+	key.RevokedAt = revokedAt.Time
+
+	return key, nil
+}
+
+func pgErrCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+
+	return ""
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
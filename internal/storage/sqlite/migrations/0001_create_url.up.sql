@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS url(
+    id INTEGER PRIMARY KEY,
+    alias TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    url TEXT NOT NULL);
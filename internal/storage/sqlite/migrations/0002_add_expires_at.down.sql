@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS url_archive;
+
+DROP INDEX IF EXISTS idx_url_expires_at;
+
+ALTER TABLE url DROP COLUMN expires_at;
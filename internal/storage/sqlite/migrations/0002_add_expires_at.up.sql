@@ -0,0 +1,10 @@
+ALTER TABLE url ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_url_expires_at ON url(expires_at);
+
+CREATE TABLE IF NOT EXISTS url_archive(
+    id INTEGER PRIMARY KEY,
+    alias TEXT NOT NULL COLLATE NOCASE,
+    url TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    archived_at TIMESTAMP NOT NULL);
@@ -0,0 +1,5 @@
+ALTER TABLE url DROP COLUMN last_accessed_at;
+
+ALTER TABLE url DROP COLUMN clicks;
+
+ALTER TABLE url DROP COLUMN created_at;
@@ -0,0 +1,5 @@
+ALTER TABLE url ADD COLUMN created_at TIMESTAMP;
+
+ALTER TABLE url ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE url ADD COLUMN last_accessed_at TIMESTAMP;
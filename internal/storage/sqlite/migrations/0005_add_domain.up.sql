@@ -0,0 +1,13 @@
+-- Домен целевого адреса для фильтра в списке ссылок. Для новых строк его
+-- заполняет приложение, существующие разбираются здесь: схема, путь, запрос,
+-- фрагмент, данные пользователя и порт отбрасываются по очереди.
+ALTER TABLE url ADD COLUMN domain TEXT NOT NULL DEFAULT '';
+
+UPDATE url SET domain = lower(substr(url, instr(url, '://') + 3)) WHERE instr(url, '://') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, '/') - 1) WHERE instr(domain, '/') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, '?') - 1) WHERE instr(domain, '?') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, '#') - 1) WHERE instr(domain, '#') > 0;
+UPDATE url SET domain = substr(domain, instr(domain, '@') + 1) WHERE instr(domain, '@') > 0;
+UPDATE url SET domain = substr(domain, 1, instr(domain, ':') - 1) WHERE instr(domain, ':') > 0;
+
+CREATE INDEX IF NOT EXISTS idx_url_clicks ON url(clicks, id);
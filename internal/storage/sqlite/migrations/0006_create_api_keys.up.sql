@@ -0,0 +1,8 @@
+-- Ключи API хранятся только в виде SHA-256 хэша, prefix нужен, чтобы отличать их в списке
+CREATE TABLE IF NOT EXISTS api_keys(
+    id INTEGER PRIMARY KEY,
+    user_name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_owner;
+
+ALTER TABLE url DROP COLUMN owner;
+
+ALTER TABLE api_keys DROP COLUMN role;
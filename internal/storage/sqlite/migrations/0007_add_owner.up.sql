@@ -0,0 +1,7 @@
+-- Владелец ссылки — имя пользователя, который ее сохранил. У существующих
+-- ссылок владельца нет, ими управляют только администраторы.
+ALTER TABLE url ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_url_owner ON url(owner, id);
+
+ALTER TABLE api_keys ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
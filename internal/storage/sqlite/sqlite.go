@@ -1,59 +1,326 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
+	"io/fs"
 	"log"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
 )
 
+var _ storage.Repository = (*Storage)(nil)
+
+// Storage пишет через одно соединение, а читает через отдельный пул: sqlite
+// допускает одного писателя, и в режиме WAL читатели его не ждут.
 type Storage struct {
-	db *sql.DB
+	db           *sql.DB // запись и миграции
+	readDB       *sql.DB // чтение, соединения с PRAGMA query_only
+	queryTimeout time.Duration
+	stmts        statements
+}
+
+// statements — запросы горячего пути, подготовленные один раз в New.
+type statements struct {
+	saveURL   *sql.Stmt
+	getURL    *sql.Stmt
+	deleteURL *sql.Stmt
 }
 
-func New(storagePath string) (*Storage, error) {
+type Options struct {
+	// QueryTimeout ограничивает время одного запроса к базе. 0 — без ограничения.
+	QueryTimeout time.Duration
+	// JournalMode — PRAGMA journal_mode: wal, delete, truncate, persist, memory
+	// или off. Пусто — как в файле базы, для новой базы это delete.
+	JournalMode string
+	// BusyTimeout — сколько соединение ждет блокировку, прежде чем вернуть
+	// SQLITE_BUSY. 0 — значение драйвера, 5 секунд.
+	BusyTimeout time.Duration
+	// Synchronous — PRAGMA synchronous: off, normal, full или extra. Пусто —
+	// normal для wal и full для остальных режимов.
+	Synchronous string
+	// ReadConns — размер пула для чтения. 0 — по числу процессоров.
+	ReadConns int
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// New открывает базу, применяет к ней все ожидающие миграции и готовит запросы.
+func New(storagePath string, opts Options) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
+	s, err := Open(storagePath, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err = migrator.Up(); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = s.prepare(); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Open открывает базу без применения миграций. Запросы к таблицам при этом
+// не готовятся, так что такое хранилище годится только для Migrator.
+func Open(storagePath string, opts Options) (*Storage, error) {
+	const op = "storage.sqlite.Open"
+
+	writeDSN, err := dsn(storagePath, opts, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite3", writeDSN)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS url(
-	    id INTEGER PRIMARY KEY,
-	    alias TEXT NOT NULL UNIQUE COLLATE NOCASE,
-	    url TEXT NOT NULL);
-	`)
+	// Второй писатель все равно ждал бы блокировку внутри sqlite
+	db.SetMaxOpenConns(1)
+
+	// У базы в памяти каждое соединение видит свою базу, поэтому пул общий
+	if inMemory(storagePath) {
+		return &Storage{db: db, readDB: db, queryTimeout: opts.QueryTimeout}, nil
+	}
+
+	readDSN, err := dsn(storagePath, opts, true)
 	if err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
+	readDB, err := sql.Open("sqlite3", readDSN)
 	if err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	readConns := opts.ReadConns
+	if readConns <= 0 {
+		readConns = runtime.NumCPU()
+	}
+
+	// Простаивающие соединения не закрываются, иначе с ними пропадали бы
+	// подготовленные на них запросы
+	readDB.SetMaxOpenConns(readConns)
+	readDB.SetMaxIdleConns(readConns)
+
+	return &Storage{db: db, readDB: readDB, queryTimeout: opts.QueryTimeout}, nil
 }
 
-func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
-	const op = "storage.sqlite.SaveURL"
+// dsn добавляет к пути параметры драйвера, которые он применяет к каждому
+// новому соединению.
+// OpenReadOnly открывает базу для migrate -dry-run: как Open, но ничего в
+// базе не меняет. Файл не создается, а JournalMode и Synchronous не
+// применяются. Если файла еще нет, открывается пустая база в памяти — для
+// нее все миграции ожидают применения.
+func OpenReadOnly(storagePath string, opts Options) (*Storage, error) {
+	const op = "storage.sqlite.OpenReadOnly"
+
+	if !inMemory(storagePath) {
+		path, _, _ := strings.Cut(strings.TrimPrefix(storagePath, "file:"), "?")
+
+		_, err := os.Stat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			storagePath = ":memory:"
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES(?, ?)")
+	readDSN, err := dsn(storagePath, Options{BusyTimeout: opts.BusyTimeout}, true)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db, err := sql.Open("sqlite3", readDSN)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db.SetMaxOpenConns(1)
+
+	return &Storage{db: db, readDB: db, queryTimeout: opts.QueryTimeout}, nil
+}
+
+func dsn(storagePath string, opts Options, readOnly bool) (string, error) {
+	params := url.Values{}
+
+	if opts.JournalMode != "" {
+		params.Set("_journal_mode", opts.JournalMode)
 	}
+	if opts.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	}
+	if opts.Synchronous != "" {
+		params.Set("_synchronous", opts.Synchronous)
+	}
+	if readOnly {
+		params.Set("_query_only", "true")
+	}
+
+	if len(params) == 0 {
+		return storagePath, nil
+	}
+
+	path, query, _ := strings.Cut(storagePath, "?")
+
+	existing, err := url.ParseQuery(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid storage path parameters: %w", err)
+	}
+
+	for key, values := range params {
+		existing[key] = values
+	}
+
+	return path + "?" + existing.Encode(), nil
+}
+
+func inMemory(storagePath string) bool {
+	return strings.Contains(storagePath, ":memory:") || strings.Contains(storagePath, "mode=memory")
+}
+
+func (s *Storage) prepare() error {
+	const op = "storage.sqlite.prepare"
+
+	for _, q := range []struct {
+		db    *sql.DB
+		query string
+		stmt  **sql.Stmt
+	}{
+		{s.db, "INSERT INTO url(url, alias, expires_at, created_at, domain, owner) VALUES(?, ?, ?, ?, ?, ?)", &s.stmts.saveURL},
+		{s.readDB, "SELECT url, expires_at FROM url WHERE alias = ?", &s.stmts.getURL},
+		{s.db, "DELETE FROM url WHERE alias = ? COLLATE NOCASE AND (? = '' OR owner = ?)", &s.stmts.deleteURL},
+	} {
+		stmt, err := q.db.Prepare(q.query)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		*q.stmt = stmt
+	}
+
+	return nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
 
-	res, err := stmt.Exec(urlToSave, alias)
+	for _, db := range []*sql.DB{s.db, s.readDB} {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	const op = "storage.sqlite.CheckSchema"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	migrator, err := s.Migrator()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = migrator.Check(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+// Close закрывает подготовленные запросы и пулы соединений, дожидаясь
+// завершения начатых запросов.
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+
+	var errs []error
+
+	for _, stmt := range []*sql.Stmt{s.stmts.saveURL, s.stmts.getURL, s.stmts.deleteURL} {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+
+	errs = append(errs, s.db.Close())
+	if s.readDB != s.db {
+		errs = append(errs, s.readDB.Close())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	const op = "storage.sqlite.Migrator"
+
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Без блокировки: базу-файл открывает один инстанс, а внутри процесса
+	// запись и так идет через единственное соединение.
+	m, err := migrate.New(s.db, fsys, migrate.Options{
+		VersionTableQuery: "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return m, nil
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	const op = "storage.sqlite.SaveURL"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.stmts.saveURL.ExecContext(ctx,
+		urlToSave, alias, nullTime(expiresAt), time.Now().UTC(), storage.URLDomain(urlToSave), owner)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrUrlExist)
 		}
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
 	}
 
 	id, err := res.LastInsertId()
@@ -64,49 +331,276 @@ func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
-	const op = "internal.storage.sqlite.GetURL"
-	var url string
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.NewURL, atomic bool) ([]storage.SaveResult, error) {
+	const op = "storage.sqlite.SaveURLs"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO url(url, alias, expires_at, created_at, domain, owner) VALUES(?, ?, ?, ?, ?, ?)")
 	if err != nil {
-		return "", fmt.Errorf("%s: statement is nil", op)
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
 	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	results := make([]storage.SaveResult, len(urls))
 
-	if stmt == nil {
-		return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	// Ошибка ограничения откатывает только свой INSERT, транзакция продолжается
+	for i, u := range urls {
+		res, err := stmt.ExecContext(ctx, u.URL, u.Alias, nullTime(u.ExpiresAt), now, storage.URLDomain(u.URL), u.Owner)
+		if err != nil {
+			if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+				results[i].Err = storage.ErrUrlExist
+				continue
+			}
+
+			return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+
+		if results[i].ID, err = res.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+		}
 	}
 
-	err = stmt.QueryRow(alias).Scan(&url)
+	if atomic && storage.Failed(results) {
+		for i := range results {
+			results[i].ID = 0
+		}
+
+		return results, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return results, nil
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+	url, _, err := s.GetURLExpiry(ctx, alias)
+
+	return url, err
+}
+
+func (s *Storage) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	const op = "internal.storage.sqlite.GetURLExpiry"
+	var url string
+	var expiresAt sql.NullTime
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	err := s.stmts.getURL.QueryRowContext(ctx, alias).Scan(&url, &expiresAt)
 	if errors.Is(err, sql.ErrNoRows) {
-		return "", storage.ErrUrlNotFound
+		return "", time.Time{}, storage.ErrUrlNotFound
 	}
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return "", time.Time{}, storage.ErrUrlExpired
 	}
 
-	return url, nil
+	return url, expiresAt.Time, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+func (s *Storage) DeleteURL(ctx context.Context, alias string, owner string) error {
 	const op = "storage.sqlite.DeleteURL"
 	log.Printf("Attempting to delete alias: %s", alias)
 
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ? COLLATE NOCASE")
-	if err != nil {
-		return fmt.Errorf("%s: prepare statement: %w", op, err)
-	}
-	defer stmt.Close()
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
 
-	res, err := stmt.Exec(alias)
+	res, err := s.stmts.deleteURL.ExecContext(ctx, alias, owner, owner)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey {
 			return fmt.Errorf("%s: %w", op, storage.ErrUrlHasReferences)
 		}
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, s.missingOrForeign(ctx, alias, owner))
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error {
+	const op = "storage.sqlite.UpdateURL"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := "UPDATE url SET url = ?, domain = ? WHERE alias = ? AND (? = '' OR owner = ?)"
+	args := []any{update.URL, storage.URLDomain(update.URL), alias, owner, owner}
+
+	if update.ExpiresAt != nil {
+		query = "UPDATE url SET url = ?, domain = ?, expires_at = ? WHERE alias = ? AND (? = '' OR owner = ?)"
+		args = []any{update.URL, storage.URLDomain(update.URL), nullTime(*update.ExpiresAt), alias, owner, owner}
+	}
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, s.missingOrForeign(ctx, alias, owner))
+	}
+
+	return nil
+}
+
+// missingOrForeign объясняет, почему запрос с ограничением по владельцу не затронул
+// ни одной строки: ссылки нет (ErrUrlNotFound) или она чужая (ErrUrlForbidden).
+func (s *Storage) missingOrForeign(ctx context.Context, alias, owner string) error {
+	if owner == "" {
+		return storage.ErrUrlNotFound
+	}
+
+	var found int
+
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM url WHERE alias = ?", alias).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return storage.ContextError(ctx, err)
+	}
+
+	return storage.ErrUrlForbidden
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.DeleteExpired"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		"DELETE FROM url WHERE expires_at IS NOT NULL AND expires_at <= ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) ArchiveExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.ArchiveExpired"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO url_archive(alias, url, expires_at, archived_at)
+	SELECT alias, url, expires_at, ? FROM url
+	WHERE expires_at IS NOT NULL AND expires_at <= ?`, time.Now().UTC(), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM url WHERE expires_at IS NOT NULL AND expires_at <= ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	archived, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return archived, nil
+}
+
+func (s *Storage) AddClicks(ctx context.Context, clicks []storage.ClickCount) error {
+	const op = "storage.sqlite.AddClicks"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"UPDATE url SET clicks = clicks + ?, last_accessed_at = ? WHERE alias = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		if _, err = stmt.ExecContext(ctx, click.Count, nullTime(click.LastAccessedAt), click.Alias); err != nil {
+			return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) RestoreStats(ctx context.Context, stats storage.URLStats) error {
+	const op = "storage.sqlite.RestoreStats"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+	UPDATE url SET
+	    clicks = ?,
+	    created_at = COALESCE(?, created_at),
+	    last_accessed_at = COALESCE(?, last_accessed_at)
+	WHERE alias = ?`,
+		stats.Clicks, nullTime(stats.CreatedAt), nullTime(stats.LastAccessedAt), stats.Alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -118,3 +612,386 @@ func (s *Storage) DeleteURL(alias string) error {
 
 	return nil
 }
+
+func (s *Storage) GetStats(ctx context.Context, alias string) (storage.URLStats, error) {
+	const op = "storage.sqlite.GetStats"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	var (
+		stats                                storage.URLStats
+		createdAt, lastAccessedAt, expiresAt sql.NullTime
+	)
+
+	err := s.readDB.QueryRowContext(ctx, `
+	SELECT alias, url, clicks, created_at, last_accessed_at, expires_at, owner
+	FROM url WHERE alias = ?`, alias,
+	).Scan(&stats.Alias, &stats.URL, &stats.Clicks, &createdAt, &lastAccessedAt, &expiresAt, &stats.Owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.URLStats{}, storage.ErrUrlNotFound
+	}
+	if err != nil {
+		return storage.URLStats{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	stats.CreatedAt = createdAt.Time
+	stats.LastAccessedAt = lastAccessedAt.Time
+	stats.ExpiresAt = expiresAt.Time
+
+	return stats, nil
+}
+
+func (s *Storage) ListURLs(ctx context.Context, opts storage.ListOptions) (storage.URLPage, error) {
+	const op = "storage.sqlite.ListURLs"
+
+	opts, cursor, err := opts.Normalize()
+	if err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	var (
+		where []string
+		args  []any
+	)
+
+	if opts.Alias != "" {
+		where = append(where, `alias LIKE ? ESCAPE '\'`)
+		args = append(args, storage.ContainsPattern(opts.Alias))
+	}
+	if opts.Domain != "" {
+		where = append(where, `domain LIKE ? ESCAPE '\'`)
+		args = append(args, storage.ContainsPattern(strings.ToLower(opts.Domain)))
+	}
+	if opts.Owner != "" {
+		where = append(where, "owner = ?")
+		args = append(args, opts.Owner)
+	}
+
+	// Колонка alias объявлена с COLLATE NOCASE, поэтому сравнение и сортировка не зависят от регистра
+	var order string
+
+	switch opts.Sort {
+	case storage.ListSortAlias:
+		order = "alias"
+		if cursor != nil {
+			where = append(where, "alias > ?")
+			args = append(args, cursor.Alias)
+		}
+	case storage.ListSortClicks:
+		order = "clicks DESC, id DESC"
+		if cursor != nil {
+			where = append(where, "(clicks, id) < (?, ?)")
+			args = append(args, cursor.Clicks, cursor.ID)
+		}
+	default:
+		order = "id DESC"
+		if cursor != nil {
+			where = append(where, "id < ?")
+			args = append(args, cursor.ID)
+		}
+	}
+
+	query := "SELECT id, alias, url, clicks, created_at, last_accessed_at, expires_at, owner FROM url"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	args = append(args, opts.Limit+1)
+
+	rows, err := s.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var (
+		page storage.URLPage
+		ids  []int64
+	)
+
+	for rows.Next() {
+		var (
+			id                                   int64
+			stats                                storage.URLStats
+			createdAt, lastAccessedAt, expiresAt sql.NullTime
+		)
+
+		err = rows.Scan(&id, &stats.Alias, &stats.URL, &stats.Clicks, &createdAt, &lastAccessedAt, &expiresAt, &stats.Owner)
+		if err != nil {
+			return storage.URLPage{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		stats.CreatedAt = createdAt.Time
+		stats.LastAccessedAt = lastAccessedAt.Time
+		stats.ExpiresAt = expiresAt.Time
+
+		page.URLs = append(page.URLs, stats)
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return storage.URLPage{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	if len(page.URLs) > opts.Limit {
+		page.URLs = page.URLs[:opts.Limit]
+		page.NextCursor = storage.EncodeCursor(opts.Sort, ids[opts.Limit-1], page.URLs[opts.Limit-1])
+	}
+
+	return page, nil
+}
+
+func (s *Storage) ForEachURL(ctx context.Context, owner string, fn func(storage.URLStats) error) error {
+	const op = "storage.sqlite.ForEachURL"
+
+	query := "SELECT alias, url, clicks, created_at, last_accessed_at, expires_at, owner FROM url"
+	var args []any
+
+	// Отдельный запрос, а не (owner = '' OR ...): так обход владельца идет по idx_url_owner
+	if owner != "" {
+		query += " WHERE owner = ?"
+		args = append(args, owner)
+	}
+
+	rows, err := s.readDB.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			stats                                storage.URLStats
+			createdAt, lastAccessedAt, expiresAt sql.NullTime
+		)
+
+		err = rows.Scan(&stats.Alias, &stats.URL, &stats.Clicks, &createdAt, &lastAccessedAt, &expiresAt, &stats.Owner)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		stats.CreatedAt = createdAt.Time
+		stats.LastAccessedAt = lastAccessedAt.Time
+		stats.ExpiresAt = expiresAt.Time
+
+		if err = fn(stats); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	const op = "storage.sqlite.SaveClickEvents"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO clicks(alias, clicked_at, referrer, user_agent, ip_hash, request_id)
+	VALUES(?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		_, err = stmt.ExecContext(ctx,
+			event.Alias, event.Time.Unix(), event.Referrer, event.UserAgent, event.IPHash, event.RequestID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *Storage) ClickTimeSeries(
+	ctx context.Context,
+	alias string,
+	from, to time.Time,
+	bucket time.Duration,
+) ([]storage.ClickBucket, error) {
+	const op = "storage.sqlite.ClickTimeSeries"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	size := int64(bucket / time.Second)
+
+	rows, err := s.readDB.QueryContext(ctx, `
+	SELECT (clicked_at / ?) * ? AS bucket, COUNT(*) FROM clicks
+	WHERE alias = ? AND clicked_at >= ? AND clicked_at < ?
+	GROUP BY bucket ORDER BY bucket`, size, size, alias, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var buckets []storage.ClickBucket
+
+	for rows.Next() {
+		var start, clicks int64
+
+		if err = rows.Scan(&start, &clicks); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		buckets = append(buckets, storage.ClickBucket{Start: time.Unix(start, 0).UTC(), Clicks: clicks})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return buckets, nil
+}
+
+// CreateAPIKey сохраняет хэш ключа: сам ключ в базу не попадает, и показать
+// его можно только при выпуске.
+func (s *Storage) CreateAPIKey(ctx context.Context, user, role, prefix, hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.CreateAPIKey"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	key := storage.APIKey{User: user, Role: role, Prefix: prefix, CreatedAt: time.Now().UTC()}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO api_keys(user_name, role, prefix, key_hash, created_at) VALUES(?, ?, ?, ?, ?)",
+		user, role, prefix, hash, key.CreatedAt,
+	)
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	if key.ID, err = res.LastInsertId(); err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func (s *Storage) FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.FindAPIKey"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	row := s.readDB.QueryRowContext(ctx, `
+	SELECT id, user_name, role, prefix, created_at, revoked_at
+	FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL`, hash)
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return key, nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	const op = "storage.sqlite.ListAPIKeys"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.readDB.QueryContext(ctx, `
+	SELECT id, user_name, role, prefix, created_at, revoked_at
+	FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var keys []storage.APIKey
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	ctx, cancel := storage.QueryContext(ctx, s.queryTimeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+		time.Now().UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ContextError(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// scanAPIKey читает строку api_keys в порядке id, user_name, role, prefix, created_at, revoked_at.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (storage.APIKey, error) {
+	var (
+		key       storage.APIKey
+		revokedAt sql.NullTime
+	)
+
+	if err := row.Scan(&key.ID, &key.User, &key.Role, &key.Prefix, &key.CreatedAt, &revokedAt); err != nil {
+		return storage.APIKey{}, err
+	}
+
+	key.RevokedAt = revokedAt.Time
+
+	return key, nil
+}
+
+// nullTime сохраняет нулевое время как NULL, остальное — в UTC,
+// чтобы строковое сравнение дат в sqlite оставалось корректным.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t.UTC()
+}
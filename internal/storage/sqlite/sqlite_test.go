@@ -0,0 +1,314 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
+	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/storage/storagetest"
+)
+
+func TestStorageContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{QueryTimeout: time.Second})
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+		return s
+	})
+}
+
+// walOptions — настройки, с которыми хранилище работает в проде.
+var walOptions = sqlite.Options{
+	QueryTimeout: time.Second,
+	JournalMode:  "wal",
+	BusyTimeout:  5 * time.Second,
+	Synchronous:  "normal",
+	ReadConns:    4,
+}
+
+func TestStorageContract_WAL(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), walOptions)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+		return s
+	})
+}
+
+func TestOpen_Pragmas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path+"?_foreign_keys=on", walOptions)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Ping(context.Background()))
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var journalMode string
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	require.Equal(t, "wal", journalMode)
+
+	_, err = sqlite.New(path, sqlite.Options{Synchronous: "sometimes"})
+	require.Error(t, err)
+}
+
+// migrate -dry-run открывает базу через OpenReadOnly и не должен ее менять.
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// Файла нет: все миграции ожидают применения, а файл не появляется
+	s, err := sqlite.OpenReadOnly(path, walOptions)
+	require.NoError(t, err)
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+
+	pending, err := m.Pending()
+	require.NoError(t, err)
+	require.NotEmpty(t, pending)
+	require.NoError(t, s.Close())
+
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// База без таблицы версий остается без нее
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE legacy(id INTEGER)")
+	require.NoError(t, err)
+
+	s, err = sqlite.OpenReadOnly(path, walOptions)
+	require.NoError(t, err)
+	defer s.Close()
+
+	m, err = s.Migrator()
+	require.NoError(t, err)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	_, err = m.Up()
+	require.Error(t, err)
+
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_version'").Scan(&tables))
+	require.Zero(t, tables)
+
+	var journalMode string
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	require.Equal(t, "delete", journalMode)
+}
+
+func TestReadsDuringWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, walOptions)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+
+	_, err = s.SaveURL(ctx, "https://example.com", "promo", time.Time{}, "")
+	require.NoError(t, err)
+
+	// Пока транзакция записи открыта, переходы читаются через отдельный пул
+	// и видят последнее подтвержденное состояние
+	writer, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	tx, err := writer.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO url(url, alias) VALUES('https://example.com/new', 'pending')")
+	require.NoError(t, err)
+
+	url, err := s.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", url)
+
+	_, err = s.GetURL(ctx, "pending")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func TestMigrationBackfillsDomain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+
+	// Откатываем схему до миграции с колонкой domain (0005) и добавляем строки, как до нее
+	_, err = m.Down(version - 4)
+	require.NoError(t, err)
+	require.ErrorIs(t, s.CheckSchema(context.Background()), migrate.ErrPending)
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO url(alias, url) VALUES
+		('plain', 'https://Example.COM/path?q=1'),
+		('userinfo', 'http://user:pw@host.test:8080#frag'),
+		('bare', 'https://bare.test')`)
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	for domain, alias := range map[string]string{
+		"example.com": "plain",
+		"host.test":   "userinfo",
+		"bare.test":   "bare",
+	} {
+		page, err := s.ListURLs(context.Background(), storage.ListOptions{Domain: domain})
+		require.NoError(t, err)
+		require.Len(t, page.URLs, 1, domain)
+		require.Equal(t, alias, page.URLs[0].Alias)
+	}
+
+	page, err := s.ListURLs(context.Background(), storage.ListOptions{Domain: "path"})
+	require.NoError(t, err)
+	require.Empty(t, page.URLs)
+}
+
+func TestClose(t *testing.T) {
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(context.Background(), "https://example.com", "closed", time.Time{}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Close())
+
+	_, err = s.GetURL(context.Background(), "closed")
+	require.Error(t, err)
+}
+
+func newBenchStorage(b *testing.B, opts sqlite.Options, aliases int) *sqlite.Storage {
+	b.Helper()
+
+	s, err := sqlite.New(filepath.Join(b.TempDir(), "storage.db"), opts)
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = s.Close() })
+
+	for i := range aliases {
+		_, err := s.SaveURL(context.Background(), "https://example.com", fmt.Sprintf("alias%d", i), time.Time{}, "")
+		require.NoError(b, err)
+	}
+
+	return s
+}
+
+var benchModes = []struct {
+	name string
+	opts sqlite.Options
+}{
+	{name: "delete", opts: sqlite.Options{QueryTimeout: time.Second}},
+	{name: "wal", opts: walOptions},
+}
+
+func BenchmarkGetURL(b *testing.B) {
+	const aliases = 1000
+
+	for _, mode := range benchModes {
+		b.Run(mode.name, func(b *testing.B) {
+			s := newBenchStorage(b, mode.opts, aliases)
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, err := s.GetURL(context.Background(), fmt.Sprintf("alias%d", i%aliases)); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSaveURL(b *testing.B) {
+	for _, mode := range benchModes {
+		b.Run(mode.name, func(b *testing.B) {
+			s := newBenchStorage(b, mode.opts, 0)
+
+			for i := 0; b.Loop(); i++ {
+				if _, err := s.SaveURL(context.Background(), "https://example.com", fmt.Sprintf("alias%d", i), time.Time{}, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetURLDuringWrites замеряет переходы, пока в фоне непрерывно
+// сохраняются новые ссылки. В режиме delete читатели ждут писателя и часть
+// чтений не укладывается в QueryTimeout: их доля — failed/op.
+func BenchmarkGetURLDuringWrites(b *testing.B) {
+	const aliases = 1000
+
+	for _, mode := range benchModes {
+		b.Run(mode.name, func(b *testing.B) {
+			s := newBenchStorage(b, mode.opts, aliases)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+
+			go func() {
+				defer close(done)
+
+				for i := 0; ctx.Err() == nil; i++ {
+					_, _ = s.SaveURL(ctx, "https://example.com", fmt.Sprintf("new%d", i), time.Time{}, "")
+				}
+			}()
+
+			var failed atomic.Int64
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, err := s.GetURL(context.Background(), fmt.Sprintf("alias%d", i%aliases)); err != nil {
+						failed.Add(1)
+					}
+					i++
+				}
+			})
+
+			b.StopTimer()
+			cancel()
+			<-done
+
+			b.ReportMetric(float64(failed.Load())/float64(b.N), "failed/op")
+		})
+	}
+}
@@ -1,9 +1,185 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	ErrUrlNotFound      = errors.New("url not found")
 	ErrUrlExist         = errors.New("url exist")
 	ErrUrlHasReferences = errors.New("url has references")
+	ErrUrlExpired       = errors.New("url expired")
+	ErrQueryTimeout     = errors.New("query timeout")
+	ErrUrlForbidden     = errors.New("url belongs to another owner")
 )
+
+// Repository — общий контракт хранилища ссылок. Каждый бэкенд должен
+// проходить набор тестов из пакета storagetest.
+//
+// Нулевой expiresAt означает ссылку без срока действия. GetURL для истекшей,
+// но еще не удаленной ссылки возвращает ErrUrlExpired.
+//
+// owner — владелец ссылки. SaveURL записывает его в ссылку, а DeleteURL и
+// UpdateURL с непустым owner меняют только ссылки этого владельца: для чужой
+// ссылки возвращается ErrUrlForbidden. Пустой owner снимает ограничение,
+// так действует администратор.
+type Repository interface {
+	SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error)
+	// SaveURLs сохраняет пачку ссылок в одной транзакции. results[i] соответствует
+	// urls[i]: id новой ссылки или ErrUrlExist. При atomic любая такая ошибка
+	// откатывает всю пачку, и id у всех элементов остаются нулевыми.
+	SaveURLs(ctx context.Context, urls []NewURL, atomic bool) ([]SaveResult, error)
+	GetURL(ctx context.Context, alias string) (string, error)
+	// GetURLExpiry — GetURL вместе со сроком действия найденной ссылки, нулевым
+	// для бессрочной. По нему кэш не держит ссылку дольше, чем она действует.
+	GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error)
+	DeleteURL(ctx context.Context, alias string, owner string) error
+	// UpdateURL меняет адрес и срок действия существующей ссылки, в том числе истекшей.
+	UpdateURL(ctx context.Context, alias string, update URLUpdate, owner string) error
+	// DeleteExpired удаляет ссылки, истекшие не позже before.
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	// ArchiveExpired переносит ссылки, истекшие не позже before, в архив.
+	ArchiveExpired(ctx context.Context, before time.Time) (int64, error)
+	// AddClicks увеличивает счетчики переходов. Неизвестные алиасы пропускаются.
+	AddClicks(ctx context.Context, clicks []ClickCount) error
+	// RestoreStats записывает в ссылку stats.Alias счетчик переходов, время
+	// создания и последнего перехода из выгрузки. Нулевое время оставляет
+	// поле как есть. Для неизвестного алиаса возвращает ErrUrlNotFound.
+	RestoreStats(ctx context.Context, stats URLStats) error
+	GetStats(ctx context.Context, alias string) (URLStats, error)
+	// ListURLs возвращает страницу ссылок, включая истекшие, но еще не удаленные.
+	// Некорректный курсор приводит к ErrInvalidCursor.
+	ListURLs(ctx context.Context, opts ListOptions) (URLPage, error)
+	// ForEachURL передает fn все ссылки по порядку id, не загружая их в память целиком.
+	// Непустой owner оставляет только ссылки этого владельца, как ListOptions.Owner.
+	// Обход не ограничен query_timeout и прерывается только ctx или ошибкой fn.
+	ForEachURL(ctx context.Context, owner string, fn func(URLStats) error) error
+	SaveClickEvents(ctx context.Context, events []ClickEvent) error
+	// ClickTimeSeries возвращает непустые интервалы длины bucket в диапазоне [from, to),
+	// упорядоченные по времени. Границы интервалов выровнены от начала эпохи в UTC.
+	ClickTimeSeries(ctx context.Context, alias string, from, to time.Time, bucket time.Duration) ([]ClickBucket, error)
+	// CreateAPIKey сохраняет хэш нового ключа пользователя user с ролью role.
+	CreateAPIKey(ctx context.Context, user, role, prefix, hash string) (APIKey, error)
+	// FindAPIKey ищет действующий ключ по хэшу. Для отозванного и неизвестного
+	// ключа возвращает ErrAPIKeyNotFound.
+	FindAPIKey(ctx context.Context, hash string) (APIKey, error)
+	// ListAPIKeys возвращает все ключи, включая отозванные, по порядку id.
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey отзывает ключ. Повторный отзыв возвращает ErrAPIKeyNotFound.
+	RevokeAPIKey(ctx context.Context, id int64) error
+	// Ping проверяет, что база доступна.
+	Ping(ctx context.Context) error
+	// CheckSchema проверяет, что к базе применены все миграции, иначе
+	// возвращает ошибку с migrate.ErrPending.
+	CheckSchema(ctx context.Context) error
+	// Close освобождает соединения с базой. После Close хранилище непригодно.
+	Close() error
+}
+
+// NewURL — ссылка для пакетного сохранения. Нулевой ExpiresAt означает ссылку без срока действия.
+type NewURL struct {
+	URL       string
+	Alias     string
+	ExpiresAt time.Time
+	Owner     string
+}
+
+type SaveResult struct {
+	ID  int64
+	Err error
+}
+
+// Failed сообщает, есть ли в пачке элементы с ошибкой.
+func Failed(results []SaveResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// URLUpdate — изменяемые поля ссылки. ExpiresAt == nil оставляет срок действия
+// без изменений, нулевое время снимает его.
+type URLUpdate struct {
+	URL       string
+	ExpiresAt *time.Time
+}
+
+// ClickCount — накопленные переходы по алиасу с момента последней записи.
+type ClickCount struct {
+	Alias          string
+	Count          int64
+	LastAccessedAt time.Time
+}
+
+// ClickEvent — один переход по ссылке. IP хранится только в виде хэша.
+type ClickEvent struct {
+	Alias     string
+	Time      time.Time
+	Referrer  string
+	UserAgent string
+	IPHash    string
+	RequestID string
+}
+
+type ClickBucket struct {
+	Start  time.Time
+	Clicks int64
+}
+
+// URLStats — статистика по ссылке. Нулевое время означает отсутствие значения.
+type URLStats struct {
+	Alias          string
+	URL            string
+	Clicks         int64
+	CreatedAt      time.Time
+	LastAccessedAt time.Time
+	ExpiresAt      time.Time
+	Owner          string
+}
+
+// QueryContext ограничивает время запроса к хранилищу. Нулевой timeout не ограничивает.
+func QueryContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ContextError приводит ошибку прерванного запроса к общему виду: при истекшем
+// дедлайне возвращает ErrQueryTimeout, при отмене — context.Canceled.
+// Если контекст жив, err возвращается без изменений.
+func ContextError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrQueryTimeout
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		return err
+	}
+}
+
+// IsFailure сообщает, что операция хранилища не удалась. Ожидаемые исходы —
+// ссылка или ключ не найдены, ссылка истекла, занята или принадлежит другому
+// владельцу, курсор некорректен — сбоями не считаются.
+func IsFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrUrlNotFound),
+		errors.Is(err, ErrUrlExist),
+		errors.Is(err, ErrUrlHasReferences),
+		errors.Is(err, ErrUrlExpired),
+		errors.Is(err, ErrUrlForbidden),
+		errors.Is(err, ErrAPIKeyNotFound),
+		errors.Is(err, ErrInvalidCursor):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,768 @@
+// Package storagetest содержит контрактные тесты для реализаций storage.Repository.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/storage"
+)
+
+// Factory возвращает хранилище для очередного теста. Алиасы в тестах
+// уникальны, поэтому хранилище может быть общим для всех запусков.
+type Factory func(t *testing.T) storage.Repository
+
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("SaveAndGet", func(t *testing.T) { testSaveAndGet(t, newRepo(t)) })
+	t.Run("AliasCaseInsensitive", func(t *testing.T) { testAliasCaseInsensitive(t, newRepo(t)) })
+	t.Run("Duplicate", func(t *testing.T) { testDuplicate(t, newRepo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("SaveBatch", func(t *testing.T) { testSaveBatch(t, newRepo(t)) })
+	t.Run("SaveBatchAtomic", func(t *testing.T) { testSaveBatchAtomic(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("Ownership", func(t *testing.T) { testOwnership(t, newRepo(t)) })
+	t.Run("ConcurrentWrites", func(t *testing.T) { testConcurrentWrites(t, newRepo(t)) })
+	t.Run("ExpiredContext", func(t *testing.T) { testExpiredContext(t, newRepo(t)) })
+	t.Run("CanceledContext", func(t *testing.T) { testCanceledContext(t, newRepo(t)) })
+	t.Run("Expired", func(t *testing.T) { testExpired(t, newRepo(t)) })
+	t.Run("DeleteExpired", func(t *testing.T) { testDeleteExpired(t, newRepo(t)) })
+	t.Run("ArchiveExpired", func(t *testing.T) { testArchiveExpired(t, newRepo(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newRepo(t)) })
+	t.Run("ForEach", func(t *testing.T) { testForEach(t, newRepo(t)) })
+	t.Run("Stats", func(t *testing.T) { testStats(t, newRepo(t)) })
+	t.Run("RestoreStats", func(t *testing.T) { testRestoreStats(t, newRepo(t)) })
+	t.Run("ClickTimeSeries", func(t *testing.T) { testClickTimeSeries(t, newRepo(t)) })
+	t.Run("APIKeys", func(t *testing.T) { testAPIKeys(t, newRepo(t)) })
+	t.Run("Health", func(t *testing.T) { testHealth(t, newRepo(t)) })
+}
+
+func newAlias(prefix string) string {
+	return prefix + "_" + random.NewRandomString(10)
+}
+
+func testSaveAndGet(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	alias := newAlias("save")
+
+	id, err := repo.SaveURL(ctx, "https://example.com/save", alias, time.Time{}, "")
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	got, err := repo.GetURL(ctx, alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/save", got)
+
+	got, expiresAt, err := repo.GetURLExpiry(ctx, alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/save", got)
+	require.True(t, expiresAt.IsZero())
+}
+
+func testAliasCaseInsensitive(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	alias := newAlias("Case")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/case", alias, time.Time{}, "")
+	require.NoError(t, err)
+
+	got, err := repo.GetURL(ctx, strings.ToUpper(alias))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/case", got)
+
+	got, err = repo.GetURL(ctx, strings.ToLower(alias))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/case", got)
+
+	require.NoError(t, repo.DeleteURL(ctx, strings.ToUpper(alias), ""))
+
+	_, err = repo.GetURL(ctx, alias)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testDuplicate(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	alias := newAlias("dup")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/first", alias, time.Time{}, "")
+	require.NoError(t, err)
+
+	_, err = repo.SaveURL(ctx, "https://example.com/second", alias, time.Time{}, "")
+	require.ErrorIs(t, err, storage.ErrUrlExist)
+
+	_, err = repo.SaveURL(ctx, "https://example.com/third", strings.ToUpper(alias), time.Time{}, "")
+	require.ErrorIs(t, err, storage.ErrUrlExist)
+
+	got, err := repo.GetURL(ctx, alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/first", got)
+}
+
+func testNotFound(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	alias := newAlias("missing")
+
+	_, err := repo.GetURL(ctx, alias)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	err = repo.DeleteURL(ctx, alias, "")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testDelete(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	alias := newAlias("delete")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/delete", alias, time.Time{}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteURL(ctx, alias, ""))
+
+	_, err = repo.GetURL(ctx, alias)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	require.ErrorIs(t, repo.DeleteURL(ctx, alias, ""), storage.ErrUrlNotFound)
+
+	// После удаления алиас снова свободен
+	_, err = repo.SaveURL(ctx, "https://example.com/again", alias, time.Time{}, "")
+	require.NoError(t, err)
+}
+
+func testSaveBatch(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	existing := newAlias("batch")
+	_, err := repo.SaveURL(ctx, "https://example.com/existing", existing, time.Time{}, "")
+	require.NoError(t, err)
+
+	first, second := newAlias("batch"), newAlias("batch")
+	expiresAt := time.Now().Add(time.Hour)
+
+	results, err := repo.SaveURLs(ctx, []storage.NewURL{
+		{URL: "https://example.com/first", Alias: first},
+		{URL: "https://example.com/conflict", Alias: strings.ToUpper(existing)},
+		{URL: "https://example.com/second", Alias: second, ExpiresAt: expiresAt},
+		{URL: "https://example.com/dup", Alias: first},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	require.NotZero(t, results[0].ID)
+	require.ErrorIs(t, results[1].Err, storage.ErrUrlExist)
+	require.NoError(t, results[2].Err)
+	require.NotZero(t, results[2].ID)
+	require.ErrorIs(t, results[3].Err, storage.ErrUrlExist, "duplicate inside the batch")
+
+	got, err := repo.GetURL(ctx, first)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/first", got)
+
+	got, err = repo.GetURL(ctx, existing)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/existing", got)
+
+	stats, err := repo.GetStats(ctx, second)
+	require.NoError(t, err)
+	require.WithinDuration(t, expiresAt, stats.ExpiresAt, time.Second)
+}
+
+func testSaveBatchAtomic(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	existing := newAlias("atomic")
+	_, err := repo.SaveURL(ctx, "https://example.com/existing", existing, time.Time{}, "")
+	require.NoError(t, err)
+
+	fresh := newAlias("atomic")
+
+	results, err := repo.SaveURLs(ctx, []storage.NewURL{
+		{URL: "https://example.com/fresh", Alias: fresh},
+		{URL: "https://example.com/conflict", Alias: existing},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Err)
+	require.Zero(t, results[0].ID)
+	require.ErrorIs(t, results[1].Err, storage.ErrUrlExist)
+
+	_, err = repo.GetURL(ctx, fresh)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound, "rejected batch must not store anything")
+
+	results, err = repo.SaveURLs(ctx, []storage.NewURL{
+		{URL: "https://example.com/fresh", Alias: fresh},
+	}, true)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.NotZero(t, results[0].ID)
+
+	got, err := repo.GetURL(ctx, fresh)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/fresh", got)
+}
+
+func testUpdate(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	alias := newAlias("Update")
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	_, err := repo.SaveURL(ctx, "https://example.com/typo", alias, expiresAt, "")
+	require.NoError(t, err)
+
+	// Без ExpiresAt срок действия не меняется
+	err = repo.UpdateURL(ctx, strings.ToUpper(alias), storage.URLUpdate{URL: "https://example.com/fixed"}, "")
+	require.NoError(t, err)
+
+	got, err := repo.GetURL(ctx, alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/fixed", got)
+
+	stats, err := repo.GetStats(ctx, alias)
+	require.NoError(t, err)
+	require.True(t, expiresAt.Equal(stats.ExpiresAt), "expires_at must be kept, got %v", stats.ExpiresAt)
+
+	// Продление истекшей ссылки снова делает ее доступной
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, repo.UpdateURL(ctx, alias, storage.URLUpdate{URL: "https://example.com/fixed", ExpiresAt: &past}, ""))
+
+	_, err = repo.GetURL(ctx, alias)
+	require.ErrorIs(t, err, storage.ErrUrlExpired)
+
+	noExpiry := time.Time{}
+	require.NoError(t, repo.UpdateURL(ctx, alias, storage.URLUpdate{URL: "https://example.com/forever", ExpiresAt: &noExpiry}, ""))
+
+	got, err = repo.GetURL(ctx, alias)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/forever", got)
+
+	stats, err = repo.GetStats(ctx, alias)
+	require.NoError(t, err)
+	require.True(t, stats.ExpiresAt.IsZero())
+
+	err = repo.UpdateURL(ctx, newAlias("missing"), storage.URLUpdate{URL: "https://example.com/missing"}, "")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testOwnership(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	alice, bob := newAlias("alice"), newAlias("bob")
+	alias := newAlias("owned")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/alice", alias, time.Time{}, alice)
+	require.NoError(t, err)
+
+	results, err := repo.SaveURLs(ctx, []storage.NewURL{
+		{URL: "https://example.com/bob", Alias: newAlias("owned"), Owner: bob},
+	}, true)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+
+	stats, err := repo.GetStats(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, alice, stats.Owner)
+
+	// Чужую ссылку нельзя ни изменить, ни удалить
+	err = repo.UpdateURL(ctx, strings.ToUpper(alias), storage.URLUpdate{URL: "https://example.com/bob"}, bob)
+	require.ErrorIs(t, err, storage.ErrUrlForbidden)
+	require.ErrorIs(t, repo.DeleteURL(ctx, alias, bob), storage.ErrUrlForbidden)
+
+	// Отсутствующая ссылка остается ErrUrlNotFound и для владельца
+	require.ErrorIs(t, repo.DeleteURL(ctx, newAlias("missing"), bob), storage.ErrUrlNotFound)
+
+	require.NoError(t, repo.UpdateURL(ctx, alias, storage.URLUpdate{URL: "https://example.com/fixed"}, alice))
+
+	url, err := repo.GetURL(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/fixed", url)
+
+	for owner, want := range map[string]string{alice: alias, bob: ""} {
+		page, err := repo.ListURLs(ctx, storage.ListOptions{Owner: owner})
+		require.NoError(t, err)
+		require.Len(t, page.URLs, 1, owner)
+		assert.Equal(t, owner, page.URLs[0].Owner)
+
+		if want != "" {
+			assert.Equal(t, want, page.URLs[0].Alias)
+		}
+	}
+
+	// Пустой owner — администратор: ограничение снято
+	require.NoError(t, repo.DeleteURL(ctx, alias, ""))
+}
+
+func testConcurrentWrites(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	const workers = 16
+
+	shared := newAlias("shared")
+	aliases := make([]string, workers)
+	for i := range aliases {
+		aliases[i] = newAlias("concurrent")
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		created   int
+		conflicts int
+		errs      []error
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+
+		go func(alias string) {
+			defer wg.Done()
+
+			if _, err := repo.SaveURL(ctx, "https://example.com/"+alias, alias, time.Time{}, ""); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(aliases[i])
+
+		go func() {
+			defer wg.Done()
+
+			_, err := repo.SaveURL(ctx, "https://example.com/shared", shared, time.Time{}, "")
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, storage.ErrUrlExist):
+				conflicts++
+			default:
+				errs = append(errs, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	require.Empty(t, errs)
+	assert.Equal(t, 1, created, "shared alias must be saved exactly once")
+	assert.Equal(t, workers-1, conflicts)
+
+	for _, alias := range aliases {
+		got, err := repo.GetURL(ctx, alias)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/"+alias, got)
+	}
+}
+
+func testExpiredContext(t *testing.T, repo storage.Repository) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	alias := newAlias("expired")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/expired", alias, time.Time{}, "")
+	require.ErrorIs(t, err, storage.ErrQueryTimeout)
+
+	_, err = repo.GetURL(ctx, alias)
+	require.ErrorIs(t, err, storage.ErrQueryTimeout)
+
+	err = repo.DeleteURL(ctx, alias, "")
+	require.ErrorIs(t, err, storage.ErrQueryTimeout)
+}
+
+func testCanceledContext(t *testing.T, repo storage.Repository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	alias := newAlias("canceled")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/canceled", alias, time.Time{}, "")
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.GetURL(ctx, alias)
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.GetURL(context.Background(), alias)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound, "canceled save must not store the url")
+}
+
+func testExpired(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	expired := newAlias("expired")
+	_, err := repo.SaveURL(ctx, "https://example.com/expired", expired, time.Now().Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	active := newAlias("active")
+	activeUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+	_, err = repo.SaveURL(ctx, "https://example.com/active", active, activeUntil, "")
+	require.NoError(t, err)
+
+	_, err = repo.GetURL(ctx, expired)
+	require.ErrorIs(t, err, storage.ErrUrlExpired)
+
+	_, _, err = repo.GetURLExpiry(ctx, expired)
+	require.ErrorIs(t, err, storage.ErrUrlExpired)
+
+	_, expiresAt, err := repo.GetURLExpiry(ctx, active)
+	require.NoError(t, err)
+	require.True(t, activeUntil.Equal(expiresAt), "expires_at %s, want %s", expiresAt, activeUntil)
+
+	_, err = repo.GetURL(ctx, strings.ToUpper(expired))
+	require.ErrorIs(t, err, storage.ErrUrlExpired)
+
+	got, err := repo.GetURL(ctx, active)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/active", got)
+
+	// Истекшая, но еще не удаленная ссылка продолжает занимать алиас
+	_, err = repo.SaveURL(ctx, "https://example.com/other", expired, time.Time{}, "")
+	require.ErrorIs(t, err, storage.ErrUrlExist)
+}
+
+func testDeleteExpired(t *testing.T, repo storage.Repository) {
+	testPurgeExpired(t, repo, repo.DeleteExpired)
+}
+
+func testArchiveExpired(t *testing.T, repo storage.Repository) {
+	testPurgeExpired(t, repo, repo.ArchiveExpired)
+}
+
+func testPurgeExpired(t *testing.T, repo storage.Repository, purge func(context.Context, time.Time) (int64, error)) {
+	ctx := context.Background()
+	now := time.Now()
+
+	expired := newAlias("purge_expired")
+	_, err := repo.SaveURL(ctx, "https://example.com/expired", expired, now.Add(-time.Minute), "")
+	require.NoError(t, err)
+
+	future := newAlias("purge_future")
+	_, err = repo.SaveURL(ctx, "https://example.com/future", future, now.Add(time.Hour), "")
+	require.NoError(t, err)
+
+	permanent := newAlias("purge_permanent")
+	_, err = repo.SaveURL(ctx, "https://example.com/permanent", permanent, time.Time{}, "")
+	require.NoError(t, err)
+
+	purged, err := purge(ctx, now)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, int64(1))
+
+	_, err = repo.GetURL(ctx, expired)
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = repo.GetURL(ctx, future)
+	require.NoError(t, err)
+
+	_, err = repo.GetURL(ctx, permanent)
+	require.NoError(t, err)
+
+	// Алиас освобождается после очистки
+	_, err = repo.SaveURL(ctx, "https://example.com/reused", expired, time.Time{}, "")
+	require.NoError(t, err)
+}
+
+func testList(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	// Общий тег изолирует выборку от ссылок других тестов в той же базе
+	tag := strings.ToLower(random.NewRandomString(8))
+
+	first, second, third := tag+"_b", tag+"_A", tag+"_c"
+
+	_, err := repo.SaveURL(ctx, "https://"+strings.ToUpper(tag)+"-one.example.com/x", first, time.Time{}, "")
+	require.NoError(t, err)
+	_, err = repo.SaveURL(ctx, "https://"+tag+"-two.example.com", second, time.Time{}, "")
+	require.NoError(t, err)
+	_, err = repo.SaveURL(ctx, "https://example.org/"+tag, third, time.Time{}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.AddClicks(ctx, []storage.ClickCount{
+		{Alias: second, Count: 5, LastAccessedAt: time.Now()},
+		{Alias: third, Count: 2, LastAccessedAt: time.Now()},
+	}))
+
+	// list проходит по всем страницам и возвращает алиасы в порядке выдачи
+	list := func(opts storage.ListOptions) []string {
+		var aliases []string
+
+		for {
+			page, err := repo.ListURLs(ctx, opts)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.URLs), opts.Limit)
+
+			for _, u := range page.URLs {
+				aliases = append(aliases, u.Alias)
+			}
+
+			if page.NextCursor == "" {
+				return aliases
+			}
+
+			opts.Cursor = page.NextCursor
+		}
+	}
+
+	require.Equal(t, []string{third, second, first},
+		list(storage.ListOptions{Alias: strings.ToUpper(tag), Limit: 2}))
+	require.Equal(t, []string{second, first, third},
+		list(storage.ListOptions{Sort: storage.ListSortAlias, Alias: tag, Limit: 1}))
+	require.Equal(t, []string{second, third, first},
+		list(storage.ListOptions{Sort: storage.ListSortClicks, Alias: tag, Limit: 2}))
+
+	// Фильтр по домену не смотрит на путь адреса
+	require.Equal(t, []string{second, first},
+		list(storage.ListOptions{Domain: tag, Limit: 10}))
+
+	page, err := repo.ListURLs(ctx, storage.ListOptions{Alias: tag + "_a", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.URLs, 1)
+	require.Equal(t, "https://"+tag+"-two.example.com", page.URLs[0].URL)
+	require.Equal(t, int64(5), page.URLs[0].Clicks)
+	require.False(t, page.URLs[0].CreatedAt.IsZero())
+
+	// Символы LIKE в фильтре ищутся буквально
+	page, err = repo.ListURLs(ctx, storage.ListOptions{Alias: tag + "%", Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, page.URLs)
+
+	_, err = repo.ListURLs(ctx, storage.ListOptions{Cursor: "garbage"})
+	require.ErrorIs(t, err, storage.ErrInvalidCursor)
+
+	page, err = repo.ListURLs(ctx, storage.ListOptions{Sort: storage.ListSortAlias, Alias: tag, Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	_, err = repo.ListURLs(ctx, storage.ListOptions{Sort: storage.ListSortClicks, Cursor: page.NextCursor})
+	require.ErrorIs(t, err, storage.ErrInvalidCursor, "cursor must not be reused with another sort")
+}
+
+func testForEach(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+
+	first, second := newAlias("each"), newAlias("each")
+	expiresAt := time.Now().Add(time.Hour)
+
+	_, err := repo.SaveURL(ctx, "https://example.com/first", first, time.Time{}, "")
+	require.NoError(t, err)
+	_, err = repo.SaveURL(ctx, "https://example.com/second", second, expiresAt, "")
+	require.NoError(t, err)
+
+	owner := newAlias("owner")
+	owned := newAlias("each")
+
+	_, err = repo.SaveURL(ctx, "https://example.com/owned", owned, time.Time{}, owner)
+	require.NoError(t, err)
+
+	var seen []storage.URLStats
+
+	err = repo.ForEachURL(ctx, "", func(u storage.URLStats) error {
+		if u.Alias == first || u.Alias == second {
+			seen = append(seen, u)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, seen, 2)
+
+	require.Equal(t, first, seen[0].Alias, "urls must be visited in insertion order")
+	require.Equal(t, "https://example.com/first", seen[0].URL)
+	require.False(t, seen[0].CreatedAt.IsZero())
+	require.True(t, seen[0].ExpiresAt.IsZero())
+	require.WithinDuration(t, expiresAt, seen[1].ExpiresAt, time.Second)
+
+	// Непустой owner оставляет только ссылки владельца
+	var ownedAliases []string
+
+	err = repo.ForEachURL(ctx, owner, func(u storage.URLStats) error {
+		ownedAliases = append(ownedAliases, u.Alias)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{owned}, ownedAliases)
+
+	// Ошибка fn прерывает обход и возвращается как есть
+	errStop := errors.New("stop")
+	calls := 0
+
+	err = repo.ForEachURL(ctx, "", func(storage.URLStats) error {
+		calls++
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, 1, calls)
+}
+
+func testStats(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	alias := newAlias("Stats")
+	expiresAt := time.Now().Add(time.Hour)
+
+	_, err := repo.SaveURL(ctx, "https://example.com/stats", alias, expiresAt, "")
+	require.NoError(t, err)
+
+	stats, err := repo.GetStats(ctx, strings.ToLower(alias))
+	require.NoError(t, err)
+	assert.Equal(t, alias, stats.Alias)
+	assert.Equal(t, "https://example.com/stats", stats.URL)
+	assert.Zero(t, stats.Clicks)
+	assert.WithinDuration(t, time.Now(), stats.CreatedAt, time.Minute)
+	assert.True(t, stats.LastAccessedAt.IsZero())
+	assert.WithinDuration(t, expiresAt, stats.ExpiresAt, time.Second)
+
+	accessedAt := time.Now().Add(-time.Second)
+
+	err = repo.AddClicks(ctx, []storage.ClickCount{
+		{Alias: strings.ToUpper(alias), Count: 3, LastAccessedAt: accessedAt},
+		{Alias: newAlias("unknown"), Count: 1, LastAccessedAt: accessedAt},
+	})
+	require.NoError(t, err)
+
+	err = repo.AddClicks(ctx, []storage.ClickCount{{Alias: alias, Count: 2, LastAccessedAt: accessedAt}})
+	require.NoError(t, err)
+
+	stats, err = repo.GetStats(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stats.Clicks)
+	assert.WithinDuration(t, accessedAt, stats.LastAccessedAt, time.Millisecond)
+
+	_, err = repo.GetStats(ctx, newAlias("missing"))
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testRestoreStats(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	alias := newAlias("Restore")
+
+	_, err := repo.SaveURL(ctx, "https://example.com/restore", alias, time.Time{}, "")
+	require.NoError(t, err)
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	accessedAt := createdAt.Add(time.Hour)
+
+	err = repo.RestoreStats(ctx, storage.URLStats{Alias: strings.ToUpper(alias), Clicks: 42, CreatedAt: createdAt, LastAccessedAt: accessedAt})
+	require.NoError(t, err)
+
+	stats, err := repo.GetStats(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), stats.Clicks)
+	assert.True(t, createdAt.Equal(stats.CreatedAt), "created_at %s", stats.CreatedAt)
+	assert.True(t, accessedAt.Equal(stats.LastAccessedAt), "last_accessed_at %s", stats.LastAccessedAt)
+
+	// Нулевое время не стирает уже записанное
+	require.NoError(t, repo.RestoreStats(ctx, storage.URLStats{Alias: alias, Clicks: 7}))
+
+	stats, err = repo.GetStats(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), stats.Clicks)
+	assert.True(t, createdAt.Equal(stats.CreatedAt))
+	assert.True(t, accessedAt.Equal(stats.LastAccessedAt))
+
+	err = repo.RestoreStats(ctx, storage.URLStats{Alias: newAlias("missing"), Clicks: 1})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func testClickTimeSeries(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	alias := newAlias("Series")
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	events := []storage.ClickEvent{
+		{Alias: alias, Time: base.Add(5 * time.Minute), Referrer: "https://ref.example", UserAgent: "test", IPHash: "h1", RequestID: "r1"},
+		{Alias: strings.ToLower(alias), Time: base.Add(59 * time.Minute)},
+		{Alias: alias, Time: base.Add(2*time.Hour + time.Minute)},
+		{Alias: alias, Time: base.Add(-time.Minute)},
+		{Alias: alias, Time: base.Add(26 * time.Hour)},
+		{Alias: newAlias("other"), Time: base.Add(time.Minute)},
+	}
+
+	require.NoError(t, repo.SaveClickEvents(ctx, events))
+
+	buckets, err := repo.ClickTimeSeries(ctx, strings.ToUpper(alias), base, base.Add(24*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.True(t, base.Equal(buckets[0].Start))
+	assert.Equal(t, int64(2), buckets[0].Clicks)
+	assert.True(t, base.Add(2*time.Hour).Equal(buckets[1].Start))
+	assert.Equal(t, int64(1), buckets[1].Clicks)
+
+	buckets, err = repo.ClickTimeSeries(ctx, alias, base.Add(-24*time.Hour), base.Add(48*time.Hour), 24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.True(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Equal(buckets[0].Start))
+	assert.Equal(t, int64(4), buckets[0].Clicks)
+	assert.Equal(t, int64(1), buckets[1].Clicks)
+
+	buckets, err = repo.ClickTimeSeries(ctx, newAlias("silent"), base, base.Add(time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, buckets)
+}
+
+func testAPIKeys(t *testing.T, repo storage.Repository) {
+	ctx := context.Background()
+	user := newAlias("team")
+	hash := random.NewRandomString(64)
+
+	created, err := repo.CreateAPIKey(ctx, user, "admin", "usk_abcd", hash)
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, user, created.User)
+	assert.Equal(t, "admin", created.Role)
+	assert.WithinDuration(t, time.Now(), created.CreatedAt, time.Minute)
+
+	found, err := repo.FindAPIKey(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, user, found.User)
+	assert.Equal(t, "admin", found.Role)
+	assert.Equal(t, "usk_abcd", found.Prefix)
+	assert.True(t, found.RevokedAt.IsZero())
+
+	_, err = repo.FindAPIKey(ctx, random.NewRandomString(64))
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	require.NoError(t, repo.RevokeAPIKey(ctx, created.ID))
+	require.ErrorIs(t, repo.RevokeAPIKey(ctx, created.ID), storage.ErrAPIKeyNotFound)
+
+	_, err = repo.FindAPIKey(ctx, hash)
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	keys, err := repo.ListAPIKeys(ctx)
+	require.NoError(t, err)
+
+	var listed *storage.APIKey
+	for i := range keys {
+		if keys[i].ID == created.ID {
+			listed = &keys[i]
+		}
+	}
+
+	require.NotNil(t, listed, "revoked key must stay in the list")
+	assert.Equal(t, user, listed.User)
+	assert.WithinDuration(t, time.Now(), listed.RevokedAt, time.Minute)
+}
+
+func testHealth(t *testing.T, repo storage.Repository) {
+	require.NoError(t, repo.Ping(context.Background()))
+	require.NoError(t, repo.CheckSchema(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, repo.Ping(ctx), context.Canceled)
+}
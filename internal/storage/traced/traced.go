@@ -0,0 +1,249 @@
+// Package traced открывает дочерний span OpenTelemetry на каждый вызов
+// хранилища, чтобы в трассе запроса было видно время базы отдельно от
+// времени обработчика.
+package traced
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/storage"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ storage.Repository = (*Storage)(nil)
+
+const instrumentationName = "url-shortener/internal/storage"
+
+var aliasKey = attribute.Key("url_shortener.alias")
+
+// Storage оборачивает хранилище. Close вызывается без span.
+type Storage struct {
+	storage.Repository
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+// New оборачивает repo. system — драйвер хранилища для атрибута
+// db.system.name: sqlite, postgres или memory.
+func New(repo storage.Repository, system string) *Storage {
+	kv := semconv.DBSystemNameKey.String(system)
+
+	switch system {
+	case "sqlite":
+		kv = semconv.DBSystemNameSQLite
+	case "postgres":
+		kv = semconv.DBSystemNamePostgreSQL
+	}
+
+	return &Storage{
+		Repository: repo,
+		tracer:     otel.Tracer(instrumentationName),
+		system:     kv,
+	}
+}
+
+func (s *Storage) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(s.system, semconv.DBOperationName(operation)),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end закрывает span. Ожидаемые исходы вроде ErrUrlNotFound ошибкой не
+// помечаются, иначе каждый промах по алиасу выглядел бы в трассах как сбой.
+func end(span trace.Span, err error) {
+	if storage.IsFailure(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if err != nil {
+		span.SetAttributes(attribute.String("url_shortener.outcome", err.Error()))
+	}
+
+	span.End()
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error) {
+	ctx, span := s.start(ctx, "SaveURL", aliasKey.String(alias))
+
+	id, err := s.Repository.SaveURL(ctx, urlToSave, alias, expiresAt, owner)
+	end(span, err)
+
+	return id, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.NewURL, atomic bool) ([]storage.SaveResult, error) {
+	ctx, span := s.start(ctx, "SaveURLs", attribute.Int("db.operation.batch.size", len(urls)))
+
+	results, err := s.Repository.SaveURLs(ctx, urls, atomic)
+	end(span, err)
+
+	return results, err
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+	ctx, span := s.start(ctx, "GetURL", aliasKey.String(alias))
+
+	url, err := s.Repository.GetURL(ctx, alias)
+	end(span, err)
+
+	return url, err
+}
+
+func (s *Storage) GetURLExpiry(ctx context.Context, alias string) (string, time.Time, error) {
+	ctx, span := s.start(ctx, "GetURLExpiry", aliasKey.String(alias))
+
+	url, expiresAt, err := s.Repository.GetURLExpiry(ctx, alias)
+	end(span, err)
+
+	return url, expiresAt, err
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string, owner string) error {
+	ctx, span := s.start(ctx, "DeleteURL", aliasKey.String(alias))
+
+	err := s.Repository.DeleteURL(ctx, alias, owner)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error {
+	ctx, span := s.start(ctx, "UpdateURL", aliasKey.String(alias))
+
+	err := s.Repository.UpdateURL(ctx, alias, update, owner)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "DeleteExpired")
+
+	n, err := s.Repository.DeleteExpired(ctx, before)
+	end(span, err)
+
+	return n, err
+}
+
+func (s *Storage) ArchiveExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "ArchiveExpired")
+
+	n, err := s.Repository.ArchiveExpired(ctx, before)
+	end(span, err)
+
+	return n, err
+}
+
+func (s *Storage) AddClicks(ctx context.Context, clicks []storage.ClickCount) error {
+	ctx, span := s.start(ctx, "AddClicks", attribute.Int("db.operation.batch.size", len(clicks)))
+
+	err := s.Repository.AddClicks(ctx, clicks)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) GetStats(ctx context.Context, alias string) (storage.URLStats, error) {
+	ctx, span := s.start(ctx, "GetStats", aliasKey.String(alias))
+
+	stats, err := s.Repository.GetStats(ctx, alias)
+	end(span, err)
+
+	return stats, err
+}
+
+func (s *Storage) ListURLs(ctx context.Context, opts storage.ListOptions) (storage.URLPage, error) {
+	ctx, span := s.start(ctx, "ListURLs")
+
+	page, err := s.Repository.ListURLs(ctx, opts)
+	end(span, err)
+
+	return page, err
+}
+
+func (s *Storage) ForEachURL(ctx context.Context, owner string, fn func(storage.URLStats) error) error {
+	ctx, span := s.start(ctx, "ForEachURL")
+
+	err := s.Repository.ForEachURL(ctx, owner, fn)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) SaveClickEvents(ctx context.Context, events []storage.ClickEvent) error {
+	ctx, span := s.start(ctx, "SaveClickEvents", attribute.Int("db.operation.batch.size", len(events)))
+
+	err := s.Repository.SaveClickEvents(ctx, events)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) ClickTimeSeries(ctx context.Context, alias string, from, to time.Time, bucket time.Duration) ([]storage.ClickBucket, error) {
+	ctx, span := s.start(ctx, "ClickTimeSeries", aliasKey.String(alias))
+
+	buckets, err := s.Repository.ClickTimeSeries(ctx, alias, from, to, bucket)
+	end(span, err)
+
+	return buckets, err
+}
+
+func (s *Storage) CreateAPIKey(ctx context.Context, user, role, prefix, hash string) (storage.APIKey, error) {
+	ctx, span := s.start(ctx, "CreateAPIKey")
+
+	key, err := s.Repository.CreateAPIKey(ctx, user, role, prefix, hash)
+	end(span, err)
+
+	return key, err
+}
+
+func (s *Storage) FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	ctx, span := s.start(ctx, "FindAPIKey")
+
+	key, err := s.Repository.FindAPIKey(ctx, hash)
+	end(span, err)
+
+	return key, err
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	ctx, span := s.start(ctx, "ListAPIKeys")
+
+	keys, err := s.Repository.ListAPIKeys(ctx)
+	end(span, err)
+
+	return keys, err
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) error {
+	ctx, span := s.start(ctx, "RevokeAPIKey")
+
+	err := s.Repository.RevokeAPIKey(ctx, id)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, span := s.start(ctx, "Ping")
+
+	err := s.Repository.Ping(ctx)
+	end(span, err)
+
+	return err
+}
+
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	ctx, span := s.start(ctx, "CheckSchema")
+
+	err := s.Repository.CheckSchema(ctx)
+	end(span, err)
+
+	return err
+}
@@ -0,0 +1,506 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/http_server/handlers/url/save"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/storage"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Политика при совпадении алиаса с существующей ссылкой.
+const (
+	// ConflictSkip оставляет существующую ссылку.
+	ConflictSkip = "skip"
+	// ConflictOverwrite заменяет адрес и срок действия существующей ссылки.
+	ConflictOverwrite = "overwrite"
+	// ConflictRename сохраняет ссылку под новым сгенерированным алиасом.
+	ConflictRename = "rename"
+)
+
+// Действия, которые попадают в отчет.
+const (
+	ActionSkipped     = "skipped"
+	ActionOverwritten = "overwritten"
+	ActionRenamed     = "renamed"
+	ActionFailed      = "failed"
+)
+
+// aliasAttempts — сколько сгенерированных алиасов пробуется, прежде чем строка считается ошибочной.
+const aliasAttempts = 3
+
+const errNegativeClicks = "field clicks must be a non-negative integer"
+
+// maxLineSize ограничивает длину строки JSONL.
+const maxLineSize = 1 << 20
+
+// Report — итог загрузки. В Rows попадают только конфликты и ошибки.
+type Report struct {
+	Imported    int         `json:"imported"`
+	Overwritten int         `json:"overwritten"`
+	Skipped     int         `json:"skipped"`
+	Failed      int         `json:"failed"`
+	Rows        []RowResult `json:"rows,omitempty"`
+}
+
+// InputError — ошибка в данных целиком, а не в отдельной строке: нет заголовка CSV,
+// слишком длинная строка JSONL и т.п.
+type InputError struct {
+	Msg string
+}
+
+func (e *InputError) Error() string {
+	return e.Msg
+}
+
+type RowResult struct {
+	Line     int    `json:"line"`
+	Alias    string `json:"alias,omitempty"`
+	Action   string `json:"action"`
+	NewAlias string `json:"new_alias,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Saver interface {
+	SaveURL(ctx context.Context, urlToSave string, alias string, expiresAt time.Time, owner string) (int64, error)
+	UpdateURL(ctx context.Context, alias string, update storage.URLUpdate, owner string) error
+	RestoreStats(ctx context.Context, stats storage.URLStats) error
+}
+
+// ImportOptions — параметры загрузки.
+type ImportOptions struct {
+	Format   string
+	Conflict string
+	// Owner становится владельцем загруженных ссылок.
+	Owner string
+	// Admin разрешает перезаписывать чужие ссылки и сохраняет владельца из
+	// колонки owner, так что выгрузка и загрузка администратором не отдает
+	// все ссылки ему. Строки без владельца получают Owner. Кроме того,
+	// восстанавливаются clicks, created_at и last_accessed_at, и выгрузка
+	// переносится без потери статистики. Без Admin ConflictOverwrite для
+	// чужой ссылки записывает в отчет ошибку, а owner и статистика
+	// игнорируются: иначе пользователь мог бы накрутить себе переходы.
+	Admin bool
+	// Aliases выдает алиасы строкам без алиаса и переименованным по ConflictRename.
+	Aliases save.AliasGenerator
+}
+
+// owner возвращает владельца новой ссылки из строки rec.
+func (o ImportOptions) owner(rec Record) string {
+	if o.Admin && rec.Owner != "" {
+		return rec.Owner
+	}
+
+	return o.Owner
+}
+
+// stats возвращает статистику строки rec для RestoreStats. false — переносить нечего.
+func (o ImportOptions) stats(rec Record) (storage.URLStats, bool) {
+	if !o.Admin || (rec.Clicks == 0 && rec.CreatedAt == nil && rec.LastAccessedAt == nil) {
+		return storage.URLStats{}, false
+	}
+
+	stats := storage.URLStats{Clicks: rec.Clicks}
+	if rec.CreatedAt != nil {
+		stats.CreatedAt = *rec.CreatedAt
+	}
+	if rec.LastAccessedAt != nil {
+		stats.LastAccessedAt = *rec.LastAccessedAt
+	}
+
+	return stats, true
+}
+
+// scope — ограничение по владельцу для перезаписи, как в storage.Repository.
+func (o ImportOptions) scope() string {
+	if o.Admin {
+		return ""
+	}
+
+	return o.Owner
+}
+
+// ValidConflict сообщает, известна ли политика конфликтов.
+func ValidConflict(conflict string) bool {
+	return conflict == ConflictSkip || conflict == ConflictOverwrite || conflict == ConflictRename
+}
+
+// Import читает ссылки из r и сохраняет их через saver. Каждая строка проверяется
+// так же, как запрос к /save; ошибочные строки пропускаются и попадают в отчет.
+// Загрузка прерывается только при ошибке чтения, истечении таймаута хранилища или отмене ctx.
+func Import(ctx context.Context, r io.Reader, opts ImportOptions, saver Saver) (Report, error) {
+	const op = "transfer.Import"
+
+	var report Report
+
+	if opts.Aliases == nil {
+		return report, fmt.Errorf("%s: alias generator is not set", op)
+	}
+
+	if !ValidConflict(opts.Conflict) {
+		return report, &InputError{Msg: fmt.Sprintf("unknown conflict policy %q", opts.Conflict)}
+	}
+
+	var (
+		records recordReader
+		err     error
+	)
+
+	switch opts.Format {
+	case FormatCSV:
+		records, err = newCSVReader(r)
+	case FormatJSONL:
+		records = newJSONLReader(r)
+	default:
+		return report, &InputError{Msg: fmt.Sprintf("unknown format %q", opts.Format)}
+	}
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	validate := validator.New()
+
+	for {
+		rec, line, err := records.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+
+		var rowErr *parseError
+		if errors.As(err, &rowErr) {
+			report.fail(line, rec.Alias, rowErr.Error())
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if rec.Clicks < 0 {
+			report.fail(line, rec.Alias, errNegativeClicks)
+			continue
+		}
+
+		req := save.Request{URL: rec.URL, Alias: rec.Alias, ExpiresAt: rec.ExpiresAt}
+
+		if err = validate.Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			if !errors.As(err, &validateErr) {
+				return report, fmt.Errorf("%s: %w", op, err)
+			}
+
+			report.fail(line, rec.Alias, resp.ValidatorError(validateErr).Error)
+			continue
+		}
+
+		expiresAt, err := req.Expiration(time.Now())
+		if err != nil {
+			report.fail(line, rec.Alias, err.Error())
+			continue
+		}
+
+		if err = req.CheckAlias(); err != nil {
+			report.fail(line, rec.Alias, err.Error())
+			continue
+		}
+
+		if err = importRow(ctx, &report, saver, line, req, expiresAt, rec, opts); err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// importRow сохраняет одну проверенную строку rec. Возвращает ошибку, только если
+// загрузку нужно прервать целиком.
+func importRow(
+	ctx context.Context,
+	report *Report,
+	saver Saver,
+	line int,
+	req save.Request,
+	expiresAt time.Time,
+	rec Record,
+	opts ImportOptions,
+) error {
+	owner := opts.owner(rec)
+
+	if req.Alias == "" {
+		alias, err := saveWithRandomAlias(ctx, saver, opts.Aliases, req.URL, expiresAt, owner)
+		if err != nil {
+			return report.storageFail(line, "", err)
+		}
+
+		report.Imported++
+
+		return report.restoreStats(ctx, saver, line, alias, rec, opts)
+	}
+
+	_, err := saver.SaveURL(ctx, req.URL, req.Alias, expiresAt, owner)
+	if err == nil {
+		report.Imported++
+		return report.restoreStats(ctx, saver, line, req.Alias, rec, opts)
+	}
+	if !errors.Is(err, storage.ErrUrlExist) {
+		return report.storageFail(line, req.Alias, err)
+	}
+
+	switch opts.Conflict {
+	case ConflictOverwrite:
+		err = saver.UpdateURL(ctx, req.Alias, storage.URLUpdate{URL: req.URL, ExpiresAt: &expiresAt}, opts.scope())
+		if err != nil {
+			return report.storageFail(line, req.Alias, err)
+		}
+
+		report.Overwritten++
+		report.Rows = append(report.Rows, RowResult{Line: line, Alias: req.Alias, Action: ActionOverwritten})
+
+		return report.restoreStats(ctx, saver, line, req.Alias, rec, opts)
+	case ConflictRename:
+		alias, err := saveWithRandomAlias(ctx, saver, opts.Aliases, req.URL, expiresAt, owner)
+		if err != nil {
+			return report.storageFail(line, req.Alias, err)
+		}
+
+		report.Imported++
+		report.Rows = append(report.Rows, RowResult{Line: line, Alias: req.Alias, Action: ActionRenamed, NewAlias: alias})
+
+		return report.restoreStats(ctx, saver, line, alias, rec, opts)
+	default:
+		report.Skipped++
+		report.Rows = append(report.Rows, RowResult{Line: line, Alias: req.Alias, Action: ActionSkipped})
+	}
+
+	return nil
+}
+
+func saveWithRandomAlias(
+	ctx context.Context,
+	saver Saver,
+	aliases save.AliasGenerator,
+	urlToSave string,
+	expiresAt time.Time,
+	owner string,
+) (string, error) {
+	var err error
+
+	for attempt := 0; attempt < aliasAttempts; attempt++ {
+		alias := aliases.Generate()
+
+		_, err = saver.SaveURL(ctx, urlToSave, alias, expiresAt, owner)
+		if err == nil {
+			return alias, nil
+		}
+		if !errors.Is(err, storage.ErrUrlExist) {
+			return "", err
+		}
+	}
+
+	return "", err
+}
+
+// restoreStats переносит в сохраненную ссылку alias статистику строки rec,
+// если это разрешено opts. Сбой не отменяет уже сохраненную ссылку: строка
+// остается загруженной, а ошибка попадает в отчет без изменения счетчиков.
+func (r *Report) restoreStats(ctx context.Context, saver Saver, line int, alias string, rec Record, opts ImportOptions) error {
+	stats, ok := opts.stats(rec)
+	if !ok {
+		return nil
+	}
+
+	stats.Alias = alias
+
+	err := saver.RestoreStats(ctx, stats)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, storage.ErrQueryTimeout) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	r.Rows = append(r.Rows, RowResult{Line: line, Alias: rec.Alias, Action: ActionFailed, Error: "failed to restore stats"})
+
+	return nil
+}
+
+func (r *Report) fail(line int, alias, msg string) {
+	r.Failed++
+	r.Rows = append(r.Rows, RowResult{Line: line, Alias: alias, Action: ActionFailed, Error: msg})
+}
+
+// storageFail записывает ошибку хранилища в отчет. Таймаут и отмена возвращаются
+// вызывающему: продолжать загрузку после них бессмысленно.
+func (r *Report) storageFail(line int, alias string, err error) error {
+	if errors.Is(err, storage.ErrQueryTimeout) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	msg := "failed to add url"
+	switch {
+	case errors.Is(err, storage.ErrUrlExist):
+		msg = "url already exist"
+	case errors.Is(err, storage.ErrUrlForbidden):
+		msg = "access denied"
+	}
+
+	r.fail(line, alias, msg)
+
+	return nil
+}
+
+type recordReader interface {
+	// Next возвращает следующую запись и номер ее строки. io.EOF означает конец
+	// данных, *parseError — ошибку в одной строке, после которой чтение продолжается.
+	Next() (Record, int, error)
+}
+
+type parseError struct {
+	msg string
+}
+
+func (e *parseError) Error() string {
+	return e.msg
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &InputError{Msg: "csv header is missing"}
+	}
+	if err != nil {
+		var csvErr *csv.ParseError
+		if errors.As(err, &csvErr) {
+			return nil, &InputError{Msg: "invalid csv header: " + csvErr.Err.Error()}
+		}
+
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["url"]; !ok {
+		return nil, &InputError{Msg: "csv header must contain url column"}
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (Record, int, error) {
+	row, err := c.r.Read()
+
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		return Record{}, csvErr.StartLine, &parseError{msg: csvErr.Err.Error()}
+	}
+	if err != nil {
+		return Record{}, 0, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+
+	rec := Record{
+		Alias: c.field(row, "alias"),
+		URL:   c.field(row, "url"),
+		Owner: c.field(row, "owner"),
+	}
+
+	if raw := c.field(row, "clicks"); raw != "" {
+		clicks, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return rec, line, &parseError{msg: errNegativeClicks}
+		}
+
+		rec.Clicks = clicks
+	}
+
+	for _, field := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{name: "created_at", dst: &rec.CreatedAt},
+		{name: "last_accessed_at", dst: &rec.LastAccessedAt},
+		{name: "expires_at", dst: &rec.ExpiresAt},
+	} {
+		raw := c.field(row, field.name)
+		if raw == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return rec, line, &parseError{msg: "field " + field.name + " must be a RFC 3339 time"}
+		}
+
+		*field.dst = &t
+	}
+
+	return rec, line, nil
+}
+
+func (c *csvReader) field(row []string, name string) string {
+	i, ok := c.columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+
+	return strings.TrimSpace(row[i])
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	return &jsonlReader{scanner: scanner}
+}
+
+func (j *jsonlReader) Next() (Record, int, error) {
+	for j.scanner.Scan() {
+		j.line++
+
+		raw := strings.TrimSpace(j.scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+			return Record{}, j.line, &parseError{msg: "invalid json"}
+		}
+
+		return rec, j.line, nil
+	}
+
+	if err := j.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Record{}, j.line, &InputError{Msg: fmt.Sprintf("line %d is too long", j.line+1)}
+		}
+
+		return Record{}, j.line, err
+	}
+
+	return Record{}, j.line, io.EOF
+}
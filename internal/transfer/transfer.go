@@ -0,0 +1,139 @@
+// Package transfer выгружает таблицу ссылок в CSV/JSONL и загружает ее обратно.
+// Используется HTTP-обработчиками /export, /import и командами export, import.
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+// Форматы выгрузки и загрузки.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// csvHeader — колонки CSV. При загрузке порядок колонок не важен, обязательна только url.
+var csvHeader = []string{"alias", "url", "clicks", "created_at", "last_accessed_at", "expires_at", "owner"}
+
+// Record — одна ссылка в выгрузке. Время в формате RFC 3339, пустые значения опускаются.
+type Record struct {
+	Alias          string     `json:"alias"`
+	URL            string     `json:"url"`
+	Clicks         int64      `json:"clicks"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	// Owner — владелец ссылки. При загрузке учитывается, только если ее
+	// выполняет администратор, см. ImportOptions.Admin.
+	Owner string `json:"owner,omitempty"`
+}
+
+type URLIterator interface {
+	ForEachURL(ctx context.Context, owner string, fn func(storage.URLStats) error) error
+}
+
+// ContentType возвращает MIME-тип формата или пустую строку для неизвестного формата.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return ""
+	}
+}
+
+// Export пишет ссылки в w построчно и возвращает их число. Непустой owner
+// оставляет в выгрузке только ссылки этого владельца.
+func Export(ctx context.Context, w io.Writer, format string, owner string, src URLIterator) (int, error) {
+	const op = "transfer.Export"
+
+	var (
+		write func(Record) error
+		flush func() error
+	)
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		write = func(rec Record) error { return cw.Write(rec.csvRow()) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+
+		write = func(rec Record) error { return enc.Encode(rec) }
+		flush = bw.Flush
+	default:
+		return 0, fmt.Errorf("%s: unknown format %q", op, format)
+	}
+
+	count := 0
+
+	err := src.ForEachURL(ctx, owner, func(u storage.URLStats) error {
+		count++
+
+		return write(Record{
+			Alias:          u.Alias,
+			URL:            u.URL,
+			Clicks:         u.Clicks,
+			CreatedAt:      timePtr(u.CreatedAt),
+			LastAccessedAt: timePtr(u.LastAccessedAt),
+			ExpiresAt:      timePtr(u.ExpiresAt),
+			Owner:          u.Owner,
+		})
+	})
+	if err != nil {
+		return count, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = flush(); err != nil {
+		return count, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+func (r Record) csvRow() []string {
+	return []string{
+		r.Alias,
+		r.URL,
+		strconv.FormatInt(r.Clicks, 10),
+		formatTime(r.CreatedAt),
+		formatTime(r.LastAccessedAt),
+		formatTime(r.ExpiresAt),
+		r.Owner,
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,300 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/transfer"
+)
+
+func newAliases(t *testing.T) *random.AliasGenerator {
+	t.Helper()
+
+	aliases, err := random.NewAliasGenerator(random.AliasOptions{Prefix: "imported-"})
+	require.NoError(t, err)
+
+	return aliases
+}
+
+// Выгрузка, загруженная администратором в пустое хранилище, выгружается
+// обратно без изменений, включая статистику.
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{transfer.FormatCSV, transfer.FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			src := memory.New()
+
+			expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+			_, err := src.SaveURL(ctx, "https://example.com/a", "first", time.Time{}, "team-a")
+			require.NoError(t, err)
+			_, err = src.SaveURL(ctx, "https://example.com/b?x=1,2", "second", expiresAt, "team-b")
+			require.NoError(t, err)
+
+			err = src.AddClicks(ctx, []storage.ClickCount{{Alias: "first", Count: 42, LastAccessedAt: time.Now().Add(-time.Minute)}})
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+
+			count, err := transfer.Export(ctx, &buf, format, "", src)
+			require.NoError(t, err)
+			require.Equal(t, 2, count)
+
+			exported := buf.String()
+
+			dst := memory.New()
+
+			report, err := transfer.Import(ctx, strings.NewReader(exported), transfer.ImportOptions{
+				Format:   format,
+				Conflict: transfer.ConflictSkip,
+				Owner:    "ops",
+				Admin:    true,
+				Aliases:  newAliases(t),
+			}, dst)
+			require.NoError(t, err)
+			require.Equal(t, transfer.Report{Imported: 2}, report)
+
+			got, err := dst.GetURL(ctx, "second")
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com/b?x=1,2", got)
+
+			buf.Reset()
+
+			_, err = transfer.Export(ctx, &buf, format, "", dst)
+			require.NoError(t, err)
+			require.Equal(t, exported, buf.String())
+
+			// Пользователь статистику не переносит
+			userDst := memory.New()
+
+			_, err = transfer.Import(ctx, strings.NewReader(exported), transfer.ImportOptions{
+				Format:   format,
+				Conflict: transfer.ConflictSkip,
+				Owner:    "team-a",
+				Aliases:  newAliases(t),
+			}, userDst)
+			require.NoError(t, err)
+
+			stats, err := userDst.GetStats(ctx, "first")
+			require.NoError(t, err)
+			require.Zero(t, stats.Clicks)
+			require.True(t, stats.LastAccessedAt.IsZero())
+		})
+	}
+}
+
+// Выгрузка и загрузка администратором сохраняет владельцев ссылок, а
+// пользователь не может назначить ссылке чужого владельца.
+func TestExportImport_Owner(t *testing.T) {
+	for _, format := range []string{transfer.FormatCSV, transfer.FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			src := memory.New()
+
+			_, err := src.SaveURL(ctx, "https://example.com/a", "team_a_link", time.Time{}, "team-a")
+			require.NoError(t, err)
+			_, err = src.SaveURL(ctx, "https://example.com/b", "team_b_link", time.Time{}, "team-b")
+			require.NoError(t, err)
+			_, err = src.SaveURL(ctx, "https://example.com/c", "legacy_link", time.Time{}, "")
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+
+			_, err = transfer.Export(ctx, &buf, format, "", src)
+			require.NoError(t, err)
+
+			exported := buf.String()
+
+			cases := []struct {
+				name     string
+				opts     transfer.ImportOptions
+				expected map[string]string
+			}{
+				{
+					name: "Admin",
+					opts: transfer.ImportOptions{Owner: "ops", Admin: true},
+					expected: map[string]string{
+						"team_a_link": "team-a",
+						"team_b_link": "team-b",
+						"legacy_link": "ops",
+					},
+				},
+				{
+					name: "User",
+					opts: transfer.ImportOptions{Owner: "team-c"},
+					expected: map[string]string{
+						"team_a_link": "team-c",
+						"team_b_link": "team-c",
+						"legacy_link": "team-c",
+					},
+				},
+			}
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					dst := memory.New()
+
+					tc.opts.Format = format
+					tc.opts.Conflict = transfer.ConflictSkip
+					tc.opts.Aliases = newAliases(t)
+
+					report, err := transfer.Import(ctx, strings.NewReader(exported), tc.opts, dst)
+					require.NoError(t, err)
+					require.Equal(t, 3, report.Imported)
+
+					for alias, owner := range tc.expected {
+						stats, err := dst.GetStats(ctx, alias)
+						require.NoError(t, err)
+						require.Equal(t, owner, stats.Owner, alias)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestExport_CSV(t *testing.T) {
+	ctx := context.Background()
+	src := memory.New()
+
+	_, err := src.SaveURL(ctx, "https://example.com", "alias", time.Time{}, "")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	_, err = transfer.Export(ctx, &buf, transfer.FormatCSV, "", src)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, "alias,url,clicks,created_at,last_accessed_at,expires_at,owner", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "alias,https://example.com,0,"), lines[1])
+}
+
+func TestImport_ConflictPolicies(t *testing.T) {
+	input := "url,alias\n" +
+		"https://example.com/new,fresh\n" +
+		"https://example.com/replacement,TAKEN\n"
+
+	cases := []struct {
+		policy      string
+		expected    transfer.Report
+		expectedURL string
+	}{
+		{
+			policy: transfer.ConflictSkip,
+			expected: transfer.Report{
+				Imported: 1,
+				Skipped:  1,
+				Rows:     []transfer.RowResult{{Line: 3, Alias: "TAKEN", Action: transfer.ActionSkipped}},
+			},
+			expectedURL: "https://example.com/original",
+		},
+		{
+			policy: transfer.ConflictOverwrite,
+			expected: transfer.Report{
+				Imported:    1,
+				Overwritten: 1,
+				Rows:        []transfer.RowResult{{Line: 3, Alias: "TAKEN", Action: transfer.ActionOverwritten}},
+			},
+			expectedURL: "https://example.com/replacement",
+		},
+		{
+			policy:      transfer.ConflictRename,
+			expectedURL: "https://example.com/original",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.policy, func(t *testing.T) {
+			ctx := context.Background()
+			repo := memory.New()
+
+			_, err := repo.SaveURL(ctx, "https://example.com/original", "taken", time.Now().Add(time.Hour), "")
+			require.NoError(t, err)
+
+			report, err := transfer.Import(ctx, strings.NewReader(input), transfer.ImportOptions{Format: transfer.FormatCSV, Conflict: tc.policy, Aliases: newAliases(t)}, repo)
+			require.NoError(t, err)
+
+			if tc.policy == transfer.ConflictRename {
+				require.Equal(t, 2, report.Imported)
+				require.Len(t, report.Rows, 1)
+				require.Equal(t, transfer.ActionRenamed, report.Rows[0].Action)
+				require.True(t, strings.HasPrefix(report.Rows[0].NewAlias, "imported-"), report.Rows[0].NewAlias)
+
+				got, err := repo.GetURL(ctx, report.Rows[0].NewAlias)
+				require.NoError(t, err)
+				require.Equal(t, "https://example.com/replacement", got)
+			} else {
+				require.Equal(t, tc.expected, report)
+			}
+
+			got, err := repo.GetURL(ctx, "taken")
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedURL, got)
+
+			_, err = repo.GetURL(ctx, "fresh")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestImport_InvalidRows(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+
+	input := `{"url": "https://example.com/ok", "alias": "ok"}
+
+{"url": "not a url", "alias": "bad"}
+{"alias": "no_url"}
+{"url": "https://example.com/past", "expires_at": "2000-01-01T00:00:00Z"}
+{"url": "https://example.com/urls", "alias": "urls"}
+{"url": "https://example.com/neg", "alias": "neg", "clicks": -1}
+{broken
+{"url": "https://example.com/generated"}
+`
+
+	report, err := transfer.Import(ctx, strings.NewReader(input), transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: transfer.ConflictSkip, Aliases: newAliases(t)}, repo)
+	require.NoError(t, err)
+
+	require.Equal(t, transfer.Report{
+		Imported: 2,
+		Failed:   6,
+		Rows: []transfer.RowResult{
+			{Line: 3, Alias: "bad", Action: transfer.ActionFailed, Error: "field URL must be a valid url"},
+			{Line: 4, Alias: "no_url", Action: transfer.ActionFailed, Error: "field URL is a required field"},
+			{Line: 5, Action: transfer.ActionFailed, Error: "field ExpiresAt must be in the future"},
+			{Line: 6, Alias: "urls", Action: transfer.ActionFailed, Error: "field Alias is reserved for a service route"},
+			{Line: 7, Alias: "neg", Action: transfer.ActionFailed, Error: "field clicks must be a non-negative integer"},
+			{Line: 8, Action: transfer.ActionFailed, Error: "invalid json"},
+		},
+	}, report)
+
+	page, err := repo.ListURLs(ctx, storage.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.URLs, 2)
+}
+
+func TestImport_InvalidInput(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := transfer.Import(ctx, strings.NewReader("alias,target\nx,y\n"), transfer.ImportOptions{Format: transfer.FormatCSV, Conflict: transfer.ConflictSkip, Aliases: newAliases(t)}, memory.New())
+	require.ErrorContains(t, err, "url column")
+
+	_, err = transfer.Import(ctx, strings.NewReader(""), transfer.ImportOptions{Format: transfer.FormatCSV, Conflict: transfer.ConflictSkip, Aliases: newAliases(t)}, memory.New())
+	require.ErrorContains(t, err, "header is missing")
+
+	_, err = transfer.Import(ctx, strings.NewReader(""), transfer.ImportOptions{Format: "xml", Conflict: transfer.ConflictSkip, Aliases: newAliases(t)}, memory.New())
+	require.Error(t, err)
+
+	_, err = transfer.Import(ctx, strings.NewReader(""), transfer.ImportOptions{Format: transfer.FormatCSV, Conflict: "merge", Aliases: newAliases(t)}, memory.New())
+	require.Error(t, err)
+}
@@ -2,21 +2,77 @@ package tests
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/gavv/httpexpect/v2"
 	"github.com/stretchr/testify/require"
 
+	"url-shortener/internal/clicks"
+	"url-shortener/internal/config"
 	"url-shortener/internal/http_server/handlers/url/save"
+	"url-shortener/internal/http_server/router"
 	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
 	"url-shortener/internal/lib/random"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage/memory"
 )
 
-const (
-	host = "localhost:8082"
-)
+// По умолчанию тесты поднимают роутер внутри процесса с хранилищем в памяти.
+// Чтобы прогнать их против запущенного сервиса, укажите его адрес:
+// TEST_HOST=localhost:8082 go test ./tests
+var host = os.Getenv("TEST_HOST")
+
+func TestMain(m *testing.M) {
+	if host != "" {
+		os.Exit(m.Run())
+	}
+
+	log := slogdiscard.NewDiscardLogger()
+	repo := memory.New()
+
+	clickCounter := clicks.NewCounter(log, repo, 10*time.Millisecond)
+	clickCounter.Start()
+
+	clickQueue, err := clicks.NewQueue(log, repo, clicks.QueueOptions{
+		Size:          100,
+		BatchSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+		Overflow:      clicks.OverflowDrop,
+	})
+	if err != nil {
+		panic(err)
+	}
+	clickQueue.Start()
+
+	aliases, err := random.NewAliasGenerator(random.AliasOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	srv := httptest.NewServer(router.New(log, config.HTTPServer{
+		User:     "us",
+		Password: "pass",
+		Batch:    config.Batch{MaxSize: 100, Mode: save.BatchAtomic},
+		// Конфиг собран без cleanenv, значения по умолчанию не подставятся
+		ExportTimeout: time.Minute,
+	}, repo, aliases, clicks.Multi{clickCounter, clickQueue}, nil, metrics.New()))
+
+	host = srv.Listener.Addr().String()
+
+	code := m.Run()
+
+	srv.Close()
+	clickCounter.Stop()
+	clickQueue.Stop()
+	os.Exit(code)
+}
 
 func TestURLShortener_HappyPath(t *testing.T) {
 	u := url.URL{
@@ -97,7 +153,7 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 			alias := tc.alias
 
 			if tc.alias == "" {
-				resp.Value("alias").String().Length().IsEqual(save.AliasLength)
+				resp.Value("alias").String().Length().IsEqual(random.DefaultAliasLength)
 				alias = resp.Value("alias").String().Raw()
 			} else {
 				resp.Value("alias").String().NotEmpty()
@@ -118,6 +174,399 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 	}
 }
 
+func TestURLShortener_SaveBatch(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	taken := random.NewRandomString(10)
+	fresh := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{URL: gofakeit.URL(), Alias: taken}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK)
+
+	batch := []save.Request{
+		{URL: "https://example.com/fresh", Alias: fresh},
+		{URL: gofakeit.URL()},
+		{URL: gofakeit.URL(), Alias: taken},
+	}
+
+	rejected := e.POST("/save/batch").
+		WithJSON(batch).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusUnprocessableEntity).
+		JSON().Object()
+
+	rejected.Value("results").Array().Value(2).Object().
+		Value("error").String().IsEqual("url already exist")
+
+	testRedirectNotFound(t, fresh)
+
+	saved := e.POST("/save/batch").
+		WithQuery("mode", "best_effort").
+		WithJSON(batch).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	saved.Value("saved").Number().IsEqual(2)
+	saved.Value("results").Array().Value(1).Object().
+		Value("alias").String().NotEmpty()
+
+	testRedirect(t, fresh, "https://example.com/fresh")
+}
+
+func TestURLShortener_ExportImport(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{URL: "https://example.com/exported", Alias: alias}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/export").
+		WithQuery("format", "csv").
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		HasContentType("text/csv").
+		Body().Contains(alias + ",https://example.com/exported,")
+
+	imported := random.NewRandomString(10)
+
+	report := e.POST("/import").
+		WithQuery("conflict", "rename").
+		WithHeader("Content-Type", "text/csv").
+		WithBytes([]byte("alias,url\n"+
+			imported+",https://example.com/imported\n"+
+			alias+",https://example.com/clash\n"+
+			"broken,not a url\n")).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	report.Value("imported").Number().IsEqual(2)
+	report.Value("failed").Number().IsEqual(1)
+	report.Value("rows").Array().Value(0).Object().
+		Value("action").String().IsEqual("renamed")
+
+	testRedirect(t, imported, "https://example.com/imported")
+	testRedirect(t, alias, "https://example.com/exported")
+
+	// Для остальных маршрутов по-прежнему разрешен только JSON
+	e.POST("/save").
+		WithHeader("Content-Type", "text/csv").
+		WithBytes([]byte("url\nhttps://example.com\n")).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusUnsupportedMediaType)
+}
+
+func TestURLShortener_APIKeys(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	created := e.POST("/admin/keys").
+		WithJSON(map[string]string{"user": "team-a"}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object()
+
+	created.Value("user").String().IsEqual("team-a")
+	key := created.Value("api_key").String().Raw()
+	id := created.Value("id").Number().Raw()
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{URL: "https://example.com/keyed", Alias: alias}).
+		WithHeader("Authorization", "Bearer "+key).
+		Expect().
+		Status(http.StatusOK)
+
+	// Ключ дает доступ к ссылкам, но не к управлению ключами
+	e.GET("/admin/keys").
+		WithHeader("Authorization", "Bearer "+key).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.GET("/admin/keys").
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("keys").Array().Last().Object().
+		Value("prefix").String().IsEqual(key[:12])
+
+	e.DELETE("/admin/keys/{id}", int64(id)).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/stats/{alias}", alias).
+		WithHeader("Authorization", "Bearer "+key).
+		Expect().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate").Contains("Bearer")
+}
+
+func TestURLShortener_Ownership(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	newKey := func(user, role string) string {
+		return e.POST("/admin/keys").
+			WithJSON(map[string]string{"user": user, "role": role}).
+			WithBasicAuth("us", "pass").
+			Expect().
+			Status(http.StatusCreated).
+			JSON().Object().
+			Value("api_key").String().Raw()
+	}
+
+	suffix := random.NewRandomString(6)
+	owner := newKey("owner-"+suffix, "user")
+	stranger := newKey("stranger-"+suffix, "user")
+	admin := newKey("admin-"+suffix, "admin")
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{URL: "https://example.com/owned", Alias: alias}).
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusOK)
+
+	e.PATCH("/url/"+alias).
+		WithJSON(map[string]any{"url": "https://example.com/hijacked"}).
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.DELETE("/delete/"+alias).
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.GET("/urls").
+		WithQuery("alias", alias).
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("urls").Array().IsEmpty()
+
+	e.GET("/urls").
+		WithQuery("alias", alias).
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("urls").Array().Value(0).Object().
+		Value("owner").String().IsEqual("owner-" + suffix)
+
+	testRedirect(t, alias, "https://example.com/owned")
+
+	// Ключ администратора управляет ключами и чужими ссылками
+	e.GET("/admin/keys").
+		WithHeader("Authorization", "Bearer "+admin).
+		Expect().
+		Status(http.StatusOK)
+
+	e.DELETE("/delete/"+alias).
+		WithHeader("Authorization", "Bearer "+admin).
+		Expect().
+		Status(http.StatusOK)
+}
+
+func TestURLShortener_Update(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{
+			URL:   "https://example.com/typo",
+			Alias: alias,
+		}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK)
+
+	e.PATCH("/url/"+alias).
+		WithJSON(map[string]any{"url": "https://example.com/fixed", "ttl": "1h"}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ContainsKey("expires_at").
+		Value("url").String().IsEqual("https://example.com/fixed")
+
+	testRedirect(t, alias, "https://example.com/fixed")
+
+	e.PATCH("/url/"+alias).
+		WithJSON(map[string]any{"url": "not a url"}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().
+		Value("error").String().IsEqual("field URL must be a valid url")
+
+	e.PATCH("/url/"+random.NewRandomString(10)).
+		WithJSON(map[string]any{"url": "https://example.com"}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func TestURLShortener_List(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	tag := strings.ToLower(random.NewRandomString(8))
+
+	for _, alias := range []string{tag + "1", tag + "2", tag + "3"} {
+		e.POST("/save").
+			WithJSON(save.Request{
+				URL:   gofakeit.URL(),
+				Alias: alias,
+			}).
+			WithBasicAuth("us", "pass").
+			Expect().
+			Status(http.StatusOK)
+	}
+
+	page := e.GET("/urls").
+		WithQuery("alias", tag).
+		WithQuery("sort", "alias").
+		WithQuery("limit", 2).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	page.Value("urls").Array().Length().IsEqual(2)
+	page.Value("urls").Array().Value(0).Object().Value("alias").String().IsEqual(tag + "1")
+
+	cursor := page.Value("next_cursor").String().NotEmpty().Raw()
+
+	page = e.GET("/urls").
+		WithQuery("alias", tag).
+		WithQuery("sort", "alias").
+		WithQuery("limit", 2).
+		WithQuery("cursor", cursor).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	page.Value("urls").Array().Length().IsEqual(1)
+	page.Value("urls").Array().Value(0).Object().Value("alias").String().IsEqual(tag + "3")
+	page.NotContainsKey("next_cursor")
+
+	e.GET("/urls").
+		WithQuery("cursor", "garbage").
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestURLShortener_Stats(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{
+			URL:   gofakeit.URL(),
+			Alias: alias,
+		}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/stats/"+alias).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ContainsKey("created_at").
+		Value("clicks").Number().IsEqual(0)
+
+	e.GET("/stats/"+random.NewRandomString(10)).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func TestURLShortener_ClickTimeSeries(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/save").
+		WithJSON(save.Request{
+			URL:   gofakeit.URL(),
+			Alias: alias,
+		}).
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK)
+
+	series := e.GET("/stats/"+alias+"/clicks").
+		WithQuery("interval", "day").
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	series.Value("interval").String().IsEqual("day")
+	series.Value("buckets").Array().Length().IsEqual(31)
+
+	e.GET("/stats/"+alias+"/clicks").
+		WithQuery("interval", "minute").
+		WithBasicAuth("us", "pass").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
 func testRedirect(t *testing.T, alias, urlToRedirect string) {
 	client := http.Client{
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -131,11 +580,10 @@ func testRedirect(t *testing.T, alias, urlToRedirect string) {
 		Path:   alias,
 	}
 
+	// Переходы открыты без аутентификации
 	req, err := http.NewRequest("GET", u.String(), nil)
 	require.NoError(t, err)
 
-	req.SetBasicAuth("us", "pass")
-
 	resp, err := client.Do(req)
 	require.NoError(t, err)
 	defer resp.Body.Close()